@@ -4,7 +4,8 @@ go 1.20
 
 require (
 	github.com/faiface/pixel v0.9.0
-	golang.org/x/image v0.0.0-20200618115811-c13761719519
+	golang.org/x/image v0.15.0
+	golang.org/x/term v0.15.0
 )
 
 require (
@@ -14,4 +15,6 @@ require (
 	github.com/go-gl/glfw v0.0.0-20221017161538-93cebf72946b // indirect
 	github.com/go-gl/mathgl v0.0.0-20190416160123-c4601bc793c7 // indirect
 	github.com/pkg/errors v0.8.1 // indirect
+	golang.org/x/sys v0.15.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
 )
@@ -1,15 +1,385 @@
+//go:build !js
+
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/faiface/pixel/pixelgl"
 	"github.com/yankooo/tetris-go/tetris"
+	"github.com/yankooo/tetris-go/tetris/netplay"
 )
 
+type game interface {
+	Initialize()
+	Run()
+}
+
+const usage = `usage:
+	tetris-go [play] [flags]      play a game
+	tetris-go replay [flags] file play back a replay saved with play -record
+	tetris-go gif [flags] file    render a replay into an animated GIF
+	tetris-go bench-bot [flags]   measure the reference bot without a window
+
+Run a command with -h to list its flags.
+`
+
 func main() {
+	args := os.Args[1:]
+	cmd := "play"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	var err error
+	switch cmd {
+	case "play":
+		err = play(args)
+	case "replay":
+		err = replay(args)
+	case "gif":
+		err = gifCommand(args)
+	case "bench-bot":
+		err = benchBot(args)
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		err = fmt.Errorf("unknown command %q", cmd)
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "tetris-go:", err)
+		os.Exit(2)
+	}
+}
+
+// configFlags adds the flags that override the config to fs. The returned
+// function loads the config and applies the flags that were set, once fs has
+// been parsed.
+func configFlags(fs *flag.FlagSet) func() (tetris.Config, error) {
+	path := fs.String("config", "", "read the config from `file` instead of the one next to the settings")
+	mode := fs.String("mode", "", "game `mode`: marathon or sprint")
+	seed := fs.Int64("seed", 0, "`seed` of the pieces, or 0 to pick one")
+	level := fs.Int("level", 0, "`level` to start on")
+	preview := fs.Int("preview", 0, "number of next pieces to show")
+	ruleset := fs.String("ruleset", "", "`ruleset`: classic or guideline")
+	ranked := fs.Bool("ranked", false, "play a ranked run, with hints turned off")
+	statsPath := fs.String("stats", "", "save the stats of the game to `file` when it ends")
+	theme := fs.String("theme", "", "draw the game with the theme in `path`, a directory or zip file")
+	pieceSet := fs.String("piece-set", "", "play with the piece `set` named, one of "+strings.Join(tetris.BuiltinPieceSets(), ", ")+", or the one in a JSON file")
+
+	return func() (tetris.Config, error) {
+		cfg, err := tetris.LoadConfig(*path)
+		if err != nil {
+			return cfg, err
+		}
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "mode":
+				cfg.Mode = tetris.Mode(*mode)
+			case "seed":
+				cfg.Seed = *seed
+			case "level":
+				cfg.Level = *level
+			case "preview":
+				cfg.Preview = *preview
+			case "ruleset":
+				cfg.Ruleset = tetris.Ruleset(*ruleset)
+			case "ranked":
+				cfg.Ranked = *ranked
+			case "stats":
+				cfg.StatsPath = *statsPath
+			case "theme":
+				cfg.Theme = *theme
+			case "piece-set":
+				cfg.PieceSet = *pieceSet
+			}
+		})
+		return cfg, cfg.Validate()
+	}
+}
+
+func play(args []string) error {
+	fs := flag.NewFlagSet("play", flag.ContinueOnError)
+	loadConfig := configFlags(fs)
+	fs.Bool("versus", false, "play a local two player match")
+	serve := fs.String("serve", "", "run a match server listening on `addr`")
+	players := fs.Int("players", 2, "number of players in each match run by -serve")
+	connect := fs.String("connect", "", "join the match server at `addr`")
+	name := fs.String("name", "player", "name sent to the match server")
+	script := fs.String("script", "", "with -connect, play headless using the actions in `file`")
+	interval := fs.Duration("interval", 200*time.Millisecond, "time between the actions of -script")
+	spectate := fs.String("spectate", "", "let spectators watch the game from `addr`")
+	watch := fs.String("watch", "", "watch the game streamed from `addr`")
+	bot := fs.Bool("bot", false, "let the reference bot play")
+	fs.Bool("tui", false, "play in the terminal instead of a window")
+	record := fs.String("record", "", "save a replay of the game to `file`")
+	helpControls := fs.Bool("help-controls", false, "print the controls of each kind of game and exit")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *helpControls {
+		fmt.Println(tetris.ControlsHelp())
+		return nil
+	}
+	kind, err := gameKind(fs)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case "serve":
+		server, err := netplay.Listen(*serve, *players)
+		if err != nil {
+			return err
+		}
+		server.Seed = cfg.Seed
+		log.Printf("serving %d player matches on %s", *players, server.Addr())
+		return server.Serve()
+	case "script":
+		f, err := os.Open(*script)
+		if err != nil {
+			return err
+		}
+		actions, err := tetris.ParseScript(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		winner, err := tetris.RunScript(*connect, *name, actions, *interval)
+		if err != nil {
+			return err
+		}
+		fmt.Println("winner:", winner)
+		return nil
+	case "tui":
+		g, err := tetris.NewTerminalGame(cfg)
+		if err != nil {
+			return err
+		}
+		if *bot {
+			g.SetBot(tetris.NewHeuristicBot())
+		}
+		g.Initialize()
+		g.Run()
+		return nil
+	}
+
+	if cfg.Theme != "" {
+		if err := tetris.UseTheme(cfg.Theme); err != nil {
+			return err
+		}
+	}
+	var tg game
+	switch kind {
+	case "versus":
+		tg, err = tetris.NewVersusGame(cfg)
+	case "connect":
+		tg = tetris.NewNetGame(*connect, *name)
+	case "watch":
+		tg = tetris.NewSpectatorGame(*watch)
+	default:
+		single, err := tetris.NewGame(cfg)
+		if err != nil {
+			return err
+		}
+		if *bot {
+			single.SetBot(tetris.NewHeuristicBot())
+		}
+		if *spectate != "" {
+			single.ServeSpectators(*spectate)
+		}
+		if *record != "" {
+			single.RecordTo(*record)
+		}
+		tg = single
+	}
+	if err != nil {
+		return err
+	}
+	run(tg)
+	return nil
+}
+
+// configFlagNames are the flags added by configFlags.
+var configFlagNames = []string{"config", "mode", "seed", "level", "preview", "ruleset", "ranked", "stats", "theme", "piece-set"}
+
+// gameFlags lists the flags each kind of game takes besides the one choosing
+// it. The options of the config that a kind of game has no use for are
+// ignored when they come from the config file, but rejected as flags.
+var gameFlags = map[string][]string{
+	"play":    append([]string{"bot", "spectate", "record"}, configFlagNames...),
+	"tui":     {"config", "mode", "seed", "level", "preview", "ruleset", "piece-set", "bot"},
+	"versus":  {"config", "seed", "level", "preview", "ruleset", "piece-set", "theme"},
+	"connect": {"config", "theme", "name"},
+	"script":  {"connect", "name", "interval"},
+	"watch":   {"config", "theme"},
+	"serve":   {"config", "seed", "players"},
+}
+
+// gameKind returns the kind of game the flags set on fs ask for, one of the
+// keys of gameFlags. Returns an error if they ask for more than one or set
+// flags that kind of game does not take.
+func gameKind(fs *flag.FlagSet) (string, error) {
+	var set []string
+	fs.Visit(func(f *flag.Flag) { set = append(set, f.Name) })
+	has := func(name string) bool {
+		for _, s := range set {
+			if s == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	kind := "play"
+	for _, k := range []string{"serve", "connect", "watch", "versus", "tui"} {
+		if !has(k) {
+			continue
+		}
+		if kind != "play" {
+			return "", fmt.Errorf("-%s and -%s cannot be used together", kind, k)
+		}
+		kind = k
+	}
+	if has("script") {
+		if kind != "connect" {
+			return "", errors.New("-script needs -connect")
+		}
+		kind = "script"
+	}
+
+	allowed := gameFlags[kind]
+	for _, name := range set {
+		ok := name == kind
+		for _, a := range allowed {
+			ok = ok || a == name
+		}
+		if ok {
+			continue
+		}
+		if kind != "play" {
+			return "", fmt.Errorf("-%s cannot be used with -%s", name, kind)
+		}
+		var kinds []string
+		for k, flags := range gameFlags {
+			for _, f := range flags {
+				if f == name && k != "play" {
+					kinds = append(kinds, "-"+k)
+				}
+			}
+		}
+		sort.Strings(kinds)
+		return "", fmt.Errorf("-%s needs %s", name, strings.Join(kinds, " or "))
+	}
+	return kind, nil
+}
+
+func replay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	headless := fs.Bool("headless", false, "print the result of the replay instead of showing it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("replay needs the replay file to play")
+	}
+	r, err := tetris.LoadReplay(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *headless {
+		b := r.Result()
+		fmt.Printf("score %d, lines %d, pieces %d, game over %v\n", b.Score(), b.Lines(), b.Pieces(), b.GameOver())
+		return nil
+	}
+	g, err := tetris.NewReplayGame(r)
+	if err != nil {
+		return err
+	}
+	run(g)
+	return nil
+}
+
+func gifCommand(args []string) error {
+	fs := flag.NewFlagSet("gif", flag.ContinueOnError)
+	out := fs.String("out", "", "file to write the GIF to (default the replay file with a .gif extension)")
+	from := fs.Float64("from", 0, "seconds of play to start at")
+	to := fs.Float64("to", 0, "seconds of play to end at (default the end of the replay)")
+	last := fs.Float64("last", 0, "render only this many seconds before the end, instead of -from")
+	fps := fs.Int("fps", 10, "frames a second, a divisor of 100")
+	scale := fs.Float64("scale", 1, "size of the GIF relative to the window")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("gif needs the replay file to render")
+	}
+	r, err := tetris.LoadReplay(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	settings, err := tetris.LoadSettings()
+	if err != nil {
+		log.Printf("using default settings: %v", err)
+	}
+	o := tetris.GIFOptions{From: *from, To: *to, FPS: *fps, Scale: *scale, Settings: settings}
+	if *last > 0 {
+		end := o.To
+		if end == 0 {
+			end = r.Duration()
+		}
+		o.From = end - *last
+		if o.From < 0 {
+			o.From = 0
+		}
+	}
+	if *out == "" {
+		*out = strings.TrimSuffix(fs.Arg(0), filepath.Ext(fs.Arg(0))) + ".gif"
+	}
+	if err := r.SaveGIF(*out, o); err != nil {
+		return err
+	}
+	fmt.Println("wrote", *out)
+	return nil
+}
+
+func benchBot(args []string) error {
+	fs := flag.NewFlagSet("bench-bot", flag.ContinueOnError)
+	games := fs.Int("games", 100, "number of games to play")
+	pieces := fs.Int("pieces", 500, "most pieces placed in each game")
+	seed := fs.Int64("seed", 1, "seed of the first game")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *games < 1 || *pieces < 1 {
+		return errors.New("bench-bot needs at least one game and one piece")
+	}
+	newBot := func() tetris.Bot { return tetris.NewHeuristicBot() }
+	fmt.Println(tetris.BenchBot(newBot, *games, *seed, *pieces))
+	return nil
+}
 
-	tg := tetris.NewGame()
+// run shows g in a window until it ends.
+func run(g game) {
 	pixelgl.Run(func() {
-		tg.Initialize()
-		tg.Run()
+		g.Initialize()
+		g.Run()
 	})
 }
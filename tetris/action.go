@@ -0,0 +1,114 @@
+package tetris
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Action is one of the moves a player can make on a Board. Applying the same
+// actions in the same order to boards created with the same seed always
+// produces the same game, so a game can be reproduced from its actions alone.
+type Action int
+
+// Various actions that can be applied to a board
+const (
+	MoveLeft Action = iota
+	MoveRight
+	Rotate
+	Gravity // One step of gravity, as made by the gravity timer or fast fall
+	HardDrop
+	RotateCCW // Rotate counter-clockwise, where Rotate turns clockwise
+	Rotate180
+)
+
+var actionNames = [...]string{
+	MoveLeft:  "left",
+	MoveRight: "right",
+	Rotate:    "rotate",
+	Gravity:   "gravity",
+	HardDrop:  "drop",
+	RotateCCW: "rotate-ccw",
+	Rotate180: "rotate-180",
+}
+
+func (a Action) String() string {
+	if !a.valid() {
+		return fmt.Sprintf("Action(%d)", int(a))
+	}
+	return actionNames[a]
+}
+
+func (a Action) valid() bool {
+	return a >= 0 && int(a) < len(actionNames)
+}
+
+// MarshalText encodes the action as its name, so actions are written by
+// name in JSON.
+func (a Action) MarshalText() ([]byte, error) {
+	if !a.valid() {
+		return nil, fmt.Errorf("invalid action %d", int(a))
+	}
+	return []byte(actionNames[a]), nil
+}
+
+// UnmarshalText decodes an action from its name.
+func (a *Action) UnmarshalText(text []byte) error {
+	parsed, err := ParseAction(string(text))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// ParseAction returns the action with the given name as printed by
+// Action.String.
+func ParseAction(name string) (Action, error) {
+	for a, n := range actionNames {
+		if n == name {
+			return Action(a), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown action %q", name)
+}
+
+// Apply performs an action on the board, including the score it earns.
+// Returns whether the action locked the active piece.
+func (b *Board) Apply(a Action) bool {
+	switch a {
+	case MoveLeft:
+		b.movePiece(-1)
+	case MoveRight:
+		b.movePiece(1)
+	case Rotate, RotateCCW, Rotate180:
+		b.rotatePiece(a)
+	case Gravity:
+		if b.applyGravity() {
+			b.AddScore(10)
+			return true
+		}
+	case HardDrop:
+		b.instafall()
+		b.AddScore(12)
+		return true
+	default:
+		panic(any("Apply(Action): Invalid action entered"))
+	}
+	return false
+}
+
+// ParseScript reads a list of action names separated by white space.
+func ParseScript(r io.Reader) ([]Action, error) {
+	var script []Action
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanWords)
+	for scanner.Scan() {
+		a, err := ParseAction(scanner.Text())
+		if err != nil {
+			return nil, err
+		}
+		script = append(script, a)
+	}
+	return script, scanner.Err()
+}
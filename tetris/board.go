@@ -2,38 +2,136 @@ package tetris
 
 import (
 	"fmt"
+	"image/color"
+	"math"
 	"math/rand"
+	"strings"
+	"time"
 
 	"github.com/faiface/pixel"
-	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/imdraw"
 	"github.com/faiface/pixel/text"
 	"github.com/yankooo/tetris-go/tetris/spritesheet"
-	"golang.org/x/image/font/basicfont"
 )
 
-// Board is an array containing the entire game board pieces.
+// Board is an array containing the entire game board pieces. The piece the
+// player controls is not part of the array until it locks: it is kept apart
+// as its kind, currentPiece, and where it is, activePose.
 type Board struct {
-	board        [22][10]Block
+	board        [22][10]Block // Locked blocks only
 	currentPiece Piece
 	nextPiece    Piece
-	activeShape  Shape // The shape that the player controls
+	preview      []Piece // The pieces after nextPiece shown in the preview
+	activeShape  Shape   // The cells the player controls, worked out from activePose
+	activePose   pose    // Where the active piece is, in the PieceDef of currentPiece
+	lastRotated  bool    // Whether the active piece last moved by rotating
 	score        int
+	lines        int // Rows cleared over the whole game
+	pieces       int // Pieces spawned over the whole game
 	gameOver     bool
 
-	blockGen          func(int) pixel.Picture
-	bgImgSprite       pixel.Sprite
-	gameBGSprite      pixel.Sprite
-	scoreBgSprite     pixel.Sprite
-	nextPieceBGSprite pixel.Sprite
+	seed           int64
+	ruleset        Ruleset
+	set            *PieceSet       // The pieces the board is played with
+	bag            []Piece         // Pieces left in the bag under the guideline ruleset
+	rng            *rand.Rand      // Piece generator, seeded so boards can share a sequence
+	garbageRng     *rand.Rand      // Picks the hole column of incoming garbage
+	pieceSrc       *countingSource // The source of rng
+	garbageSrc     *countingSource // The source of garbageRng
+	pendingGarbage []int           // Garbage rows waiting to rise, one entry per attack
+	outgoingAttack int             // Garbage rows sent that the opponent has not taken yet
+
+	events *EventBus // Where the board publishes what happens on it
+
+	skin  *skin          // The theme the board is drawn with
+	lang  Language       // The language of the text drawn
+	ghost GhostStyle     // How the ghost of the active piece is drawn
+	batch *pixel.Batch   // Collects block sprites to draw them in one go
+	txt   *text.Text     // Reused for each piece of text drawn
+	imd   *imdraw.IMDraw // Reused for each set of shapes drawn
+
+	ghostColor  color.RGBA // Colour of the ghost, if customGhost is set
+	customGhost bool       // Whether the ghost is drawn in ghostColor rather than the piece's colour
 }
 
 func NewBoard() *Board {
-	b := &Board{}
-	b.nextPiece = Piece(rand.Intn(7))
+	return NewSeededBoard(time.Now().UnixNano())
+}
+
+// NewSeededBoard creates a board whose pieces are drawn from a generator
+// seeded with seed. Boards created with the same seed receive the same
+// sequence of pieces.
+func NewSeededBoard(seed int64) *Board {
+	return newRuledBoard(seed, Classic, 1, Tetrominoes)
+}
+
+// garbageSeedMask turns the seed of a board into the seed of its garbage
+// holes, so that the holes cannot be told from the pieces dealt.
+const garbageSeedMask = 0x5deece66d
+
+// newRuledBoard creates a board that deals pieces of set by the given
+// ruleset and shows preview next pieces. Boards created with the same seed,
+// ruleset, preview and set receive the same sequence of pieces.
+func newRuledBoard(seed int64, ruleset Ruleset, preview int, set *PieceSet) *Board {
+	b := &Board{seed: seed, ruleset: ruleset, set: set}
+	b.pieceSrc = newCountingSource(seed)
+	b.garbageSrc = newCountingSource(seed ^ garbageSeedMask)
+	b.rng = rand.New(b.pieceSrc)
+	b.garbageRng = rand.New(b.garbageSrc)
+	b.nextPiece = b.dealPiece()
+	for i := 1; i < preview; i++ {
+		b.preview = append(b.preview, b.dealPiece())
+	}
 	b.gameOver = false
 	return b
 }
 
+// dealPiece picks the piece that joins the end of the queue. The guideline
+// ruleset deals every piece of the set once from a shuffled bag before
+// refilling it.
+func (b *Board) dealPiece() Piece {
+	if b.ruleset != Guideline {
+		return Piece(b.rng.Intn(len(b.set.Pieces)))
+	}
+	if len(b.bag) == 0 {
+		for _, p := range b.rng.Perm(len(b.set.Pieces)) {
+			b.bag = append(b.bag, Piece(p))
+		}
+	}
+	p := b.bag[0]
+	b.bag = b.bag[1:]
+	return p
+}
+
+// countingSource is a random source that counts the numbers drawn from it,
+// so that its state can be saved as the seed and the count.
+type countingSource struct {
+	src   rand.Source
+	draws int64
+}
+
+func newCountingSource(seed int64) *countingSource {
+	return &countingSource{src: rand.NewSource(seed)}
+}
+
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+func (s *countingSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.draws = 0
+}
+
+// skip draws n numbers, bringing a freshly seeded source to where a saved
+// one was.
+func (s *countingSource) skip(n int64) {
+	for s.draws < n {
+		s.Int63()
+	}
+}
+
 func (b *Board) AddScore(score int) {
 	b.score += score
 }
@@ -42,73 +140,83 @@ func (b *Board) AddScore(score int) {
 // directly below it. Used to give the user more time when placing block on
 // floor
 func (b *Board) isTouchingFloor() bool {
-	blockType := b.board[b.activeShape[0].row][b.activeShape[0].col]
-	b.drawPiece(b.activeShape, Empty)
-	isTouching := b.checkCollision(moveShapeDown(b.activeShape))
-	b.drawPiece(b.activeShape, blockType)
-	return isTouching
-}
-
-// rotatePiece rotates the piece that the user is currently moving clockwise by
-// 90 degrees. The rotation is made and collision is checked. If the rotation can
-// be completed by moving the newly rotated shape, the rotation will also be
-// performed. If it is impossible to rotate, does nothing.
-func (b *Board) rotatePiece() {
-	// The O piece should not be rotated
-	if b.currentPiece == OPiece {
-		return
+	return b.checkCollision(moveShapeDown(b.activeShape))
+}
+
+// rotatePiece turns the piece that the user is currently moving as the
+// rotation action a says. The rotation is made and collision is checked. If
+// the rotation can be completed by moving the newly rotated shape, the
+// rotation will also be performed. If it is impossible to rotate, does
+// nothing.
+func (b *Board) rotatePiece(a Action) {
+	if ps, ok := b.kickRotation(b.activePose, a); ok {
+		b.setActive(ps)
+		b.lastRotated = true
+		b.events.publish(PieceRotated{Shape: b.activeShape})
+	}
+}
+
+// kickRotation turns the active piece from pose ps as the rotation action a
+// says and checks it for collision. If the rotated shape collides it is
+// moved by each offset of the piece's kick table in turn and the first that
+// fits is used. Returns false if there is no room to rotate, or the piece
+// looks the same after turning, like the O.
+func (b *Board) kickRotation(ps pose, a Action) (pose, bool) {
+	def := b.active()
+	state := def.turn(ps.state, a)
+	if state == ps.state {
+		return ps, false
+	}
+	kicks := def.Kicks.Quarter
+	if a == Rotate180 {
+		kicks = def.Kicks.Half
 	}
-	blockType := b.board[b.activeShape[0].row][b.activeShape[0].col]
-	// Erase Piece
-	b.drawPiece(b.activeShape, Empty)
-
-	// Get the new shape and check for it's collision
-	newShape := rotateShape(b.activeShape)
-	if b.checkCollision(newShape) {
-		if !b.checkCollision(moveShapeRight(newShape)) {
-			newShape = moveShapeRight(newShape)
-		} else if !b.checkCollision(moveShapeLeft(newShape)) {
-			newShape = moveShapeLeft(newShape)
-		} else if !b.checkCollision(moveShapeDown(newShape)) {
-			newShape = moveShapeDown(newShape)
-		} else {
-			b.drawPiece(b.activeShape, blockType)
-			return
+	for _, k := range kicks {
+		turned := pose{state: state, row: ps.row + k.row, col: ps.col + k.col}
+		if !b.checkCollision(def.shape(turned)) {
+			return turned, true
 		}
 	}
-	b.activeShape = newShape
-	b.drawPiece(b.activeShape, blockType)
+	return ps, false
 }
 
-// movePiece attemps to move the piece that the user is controlling either
-// right or left. +1 signifies a right move while -1 signifies a left move
-func (b *Board) movePiece(dir int) {
-	blockType := b.board[b.activeShape[0].row][b.activeShape[0].col]
+// active returns the definition of the piece the user is controlling.
+func (b *Board) active() *PieceDef {
+	return b.set.def(b.currentPiece)
+}
 
-	// Erase old piece
-	b.drawPiece(b.activeShape, Empty)
+// setActive moves the active piece to pose ps.
+func (b *Board) setActive(ps pose) {
+	b.activePose = ps
+	b.activeShape = b.active().shape(ps)
+}
 
-	// Check collision
-	didCollide := b.checkCollision(moveShape(0, dir, b.activeShape))
-	if !didCollide {
-		b.activeShape = moveShape(0, dir, b.activeShape)
-	}
-	b.drawPiece(b.activeShape, blockType)
+// pieceBlock returns the colour of the blocks of piece p.
+func (b *Board) pieceBlock(p Piece) Block {
+	return b.set.def(p).Block
 }
 
-// drawPiece sets the values of a board, b, to a specific block type, t
-// according to shape, s.
-func (b *Board) drawPiece(s Shape, t Block) {
-	for i := 0; i < 4; i++ {
-		b.board[b.activeShape[i].row][b.activeShape[i].col] = t
+// movePiece attemps to move the piece that the user is controlling either
+// right or left. +1 signifies a right move while -1 signifies a left move
+func (b *Board) movePiece(dir int) {
+	if b.checkCollision(moveShape(0, dir, b.activeShape)) {
+		return
 	}
+	b.setActive(pose{state: b.activePose.state, row: b.activePose.row, col: b.activePose.col + dir})
+	b.lastRotated = false
+	a := MoveRight
+	if dir < 0 {
+		a = MoveLeft
+	}
+	b.events.publish(PieceMoved{Shape: b.activeShape, Action: a})
 }
 
-// checkCollision checks if at the 4 points of a shape, s, there is
+// checkCollision checks if at the points of a shape, s, there is
 // nothing but Empty value under it and the position of the shape
-// is inside the playing board (10x22 (top two rows invisiable)).
-func (b Board) checkCollision(s Shape) bool {
-	for i := 0; i < 4; i++ {
+// is inside the playing board (10x22 (top two rows invisiable)). Only locked
+// blocks are in the way, never the active piece.
+func (b *Board) checkCollision(s Shape) bool {
+	for i := range s {
 		r := s[i].row
 		c := s[i].col
 		if r < 0 || r > 21 || c < 0 || c > 9 || b.board[r][c] != Empty {
@@ -122,28 +230,56 @@ func (b Board) checkCollision(s Shape) bool {
 // is detected place the piece down and add a new piece. Returns wheather
 // a collision was made.
 func (b *Board) applyGravity() bool {
-	blockType := b.board[b.activeShape[0].row][b.activeShape[0].col]
-	// Erase old piece
-	b.drawPiece(b.activeShape, Empty)
-
 	// Does the block collide if it moves down?
-	didCollide := b.checkCollision(moveShapeDown(b.activeShape))
+	if !b.checkCollision(moveShapeDown(b.activeShape)) {
+		b.setActive(pose{state: b.activePose.state, row: b.activePose.row - 1, col: b.activePose.col})
+		b.lastRotated = false
+		b.events.publish(PieceMoved{Shape: b.activeShape, Action: Gravity})
+		return false
+	}
 
-	if !didCollide {
-		b.activeShape = moveShapeDown(b.activeShape)
+	toppedOut := isGameOver(b.activeShape)
+	locked := PieceLocked{Piece: b.currentPiece, Shape: b.activeShape, TSpin: b.isTSpin()}
+	b.fillShape(b.activeShape, b.active().Block)
+	locked.Cleared = b.checkRowCompletion(b.activeShape)
+	b.events.publish(locked)
+	if locked.Cleared > 0 {
+		b.events.publish(LinesCleared{Rows: locked.Cleared, TSpin: locked.TSpin})
 	}
+	b.settleGarbage(locked.Cleared)
+	b.AddPiece() // Replace with random piece
+	if toppedOut {
+		b.endGame()
+	}
+	return true
+}
 
-	b.drawPiece(b.activeShape, blockType)
+// endGame marks the game as over, letting subscribers know the first time.
+func (b *Board) endGame() {
+	if b.gameOver {
+		return
+	}
+	b.gameOver = true
+	b.events.publish(GameEnded{Score: b.score})
+}
 
-	if didCollide {
-		if isGameOver(b.activeShape) {
-			b.gameOver = true
+// isTSpin checks if the active piece is one that makes T-spins and got to
+// where it is by rotating, with at least three of the four cells diagonal to
+// its centre filled. The walls and floor count as filled.
+func (b *Board) isTSpin() bool {
+	if !b.active().TSpin || !b.lastRotated {
+		return false
+	}
+	pivot := b.activeShape[1]
+	filled := 0
+	for _, d := range [4]Point{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}} {
+		r := pivot.row + d.row
+		c := pivot.col + d.col
+		if r < 0 || r >= BoardRows || c < 0 || c >= BoardCols || b.board[r][c] != Empty {
+			filled++
 		}
-		b.checkRowCompletion(b.activeShape)
-		b.AddPiece() // Replace with random piece
-		return true
 	}
-	return false
+	return filled >= 3
 }
 
 // instafall calls the applyGravity function until a collision is detected.
@@ -155,8 +291,8 @@ func (b *Board) instafall() {
 }
 
 // checkRowCompletion checks if the rows in a given shape are filled (ie should
-// be deleted). If full, deletes the rows.
-func (b *Board) checkRowCompletion(s Shape) {
+// be deleted). If full, deletes the rows. Returns the number of rows deleted.
+func (b *Board) checkRowCompletion(s Shape) int {
 	// Ony the rows of the shape can be filled
 	rowWasDeleted := true
 	// Since when we delete a row it can be shifted down, repeatedly try
@@ -164,7 +300,7 @@ func (b *Board) checkRowCompletion(s Shape) {
 	var deleteRowCt int
 	for rowWasDeleted {
 		rowWasDeleted = false
-		for i := 0; i < 4; i++ {
+		for i := range s {
 			r := s[i].row
 			emptyFound := false
 			// Look for empty row
@@ -179,6 +315,7 @@ func (b *Board) checkRowCompletion(s Shape) {
 				b.deleteRow(r)
 				rowWasDeleted = true
 				b.score += 200
+				b.lines++
 				deleteRowCt++
 			}
 		}
@@ -187,56 +324,70 @@ func (b *Board) checkRowCompletion(s Shape) {
 	if deleteRowCt > 1 {
 		b.score += (deleteRowCt - 1) * 200
 	}
+	return deleteRowCt
 }
 
-// deleteRow remoes a row by shifting everything above it down by one.
+// deleteRow remoes a row by shifting everything above it down by one. The
+// top row is left empty.
 func (b *Board) deleteRow(row int) {
 	for r := row; r < 21; r++ {
-		for c := 0; c < 10; c++ {
-			b.board[r][c] = b.board[r+1][c]
-		}
+		b.board[r] = b.board[r+1]
 	}
+	b.board[21] = [BoardCols]Block{}
 }
 
-// setPiece sets a value in the game board to a specific block type.
-func (b *Board) setPiece(r, c int, val Block) {
-	b.board[r][c] = val
-}
-
-// fillShape sets
+// fillShape locks the cells of shape s into the board as blocks of val.
 func (b *Board) fillShape(s Shape, val Block) {
-	for i := 0; i < 4; i++ {
-		b.setPiece(s[i].row, s[i].col, val)
+	for _, p := range s {
+		b.board[p.row][p.col] = val
 	}
 }
 
 // addPiece creates a piece at the top of the screen at a random position
 // and sets it to the piece that the player is controlling
-// (ie b.activeShape).
+// (ie b.activeShape). The piece spawns in its first state, moved up by its
+// Spawn rows.
 func (b *Board) AddPiece() {
-	var offset int
-	if b.nextPiece == IPiece {
-		offset = rand.Intn(7)
-	} else if b.nextPiece == OPiece {
-		offset = rand.Intn(9)
+	def := b.set.def(b.nextPiece)
+	_, _, minCol, maxCol := shapeBounds(def.States[0])
+	offset := b.rng.Intn(BoardCols - (maxCol - minCol))
+	b.currentPiece = b.nextPiece
+	b.setActive(pose{row: def.Spawn, col: offset - minCol})
+	if len(b.preview) > 0 {
+		b.nextPiece = b.preview[0]
+		b.preview = append(b.preview[1:], b.dealPiece())
 	} else {
-		offset = rand.Intn(8)
+		b.nextPiece = b.dealPiece()
+	}
+	b.pieces++
+	b.events.publish(PieceSpawned{Piece: b.currentPiece, Shape: b.activeShape})
+	// A piece with no room to appear tops the player out
+	if b.checkCollision(b.activeShape) {
+		b.endGame()
 	}
-	baseShape := getShapeFromPiece(b.nextPiece)
-	baseShape = moveShape(20, offset, baseShape)
-	b.fillShape(baseShape, piece2Block(b.nextPiece))
-	b.currentPiece = b.nextPiece
-	b.activeShape = baseShape
-	b.nextPiece = Piece(rand.Intn(7))
+}
+
+// reset clears the board for a new game, keeping its piece generator and
+// resources.
+func (b *Board) reset() {
+	b.board = [22][10]Block{}
+	b.score = 0
+	b.lines = 0
+	b.pieces = 0
+	b.lastRotated = false
+	b.gameOver = false
+	b.pendingGarbage = nil
+	b.outgoingAttack = 0
+	b.AddPiece()
 }
 
 // displayBoard displays a particular game board with all of its pieces
 // onto a given window, win
-func (b *Board) displayBoard(win *pixelgl.Window) {
-	boardBlockSize := 20.0 //win.Bounds().Max.X / 10
-	pic := b.blockGen(0)
-	imgSize := pic.Bounds().Max.X
-	scaleFactor := float64(boardBlockSize) / float64(imgSize)
+func (b *Board) displayBoard(win pixel.Target) {
+	b.displayGhost(win)
+
+	scaleFactor := b.skin.scale
+	b.batch.Clear()
 
 	for col := 0; col < BoardCols; col++ {
 		for row := 0; row < BoardRows-2; row++ {
@@ -245,35 +396,19 @@ func (b *Board) displayBoard(win *pixelgl.Window) {
 				continue
 			}
 
-			x := float64(col)*boardBlockSize + boardBlockSize/2
-			y := float64(row)*boardBlockSize + boardBlockSize/2
-			pic := b.blockGen(block2spriteIdx(val))
-			sprite := pixel.NewSprite(pic, pic.Bounds())
-			sprite.Draw(win, pixel.IM.Scaled(pixel.ZV, scaleFactor).Moved(pixel.V(x+282, y+25)))
+			b.skin.block(val).Draw(b.batch, pixel.IM.Scaled(pixel.ZV, scaleFactor).Moved(screenLayout.cell(row, col)))
 		}
 	}
-
-	// Display Shadow
-	pieceType := b.board[b.activeShape[0].row][b.activeShape[0].col]
-	ghostShape := b.activeShape
-	b.drawPiece(b.activeShape, Empty)
-	for {
-		if b.checkCollision(moveShapeDown(ghostShape)) {
-			break
-		}
-		ghostShape = moveShapeDown(ghostShape)
-	}
-	b.drawPiece(b.activeShape, pieceType)
-
-	gpic := b.blockGen(block2spriteIdx(Gray))
-	sprite := pixel.NewSprite(gpic, gpic.Bounds())
-	for i := 0; i < 4; i++ {
-		if b.board[ghostShape[i].row][ghostShape[i].col] == Empty {
-			x := float64(ghostShape[i].col)*boardBlockSize + boardBlockSize/2
-			y := float64(ghostShape[i].row)*boardBlockSize + boardBlockSize/2
-			sprite.Draw(win, pixel.IM.Scaled(pixel.ZV, scaleFactor/2).Moved(pixel.V(x+282, y+25)))
+	sprite := b.skin.block(b.pieceBlock(b.currentPiece))
+	for _, p := range b.activeShape {
+		if p.row < BoardRows-2 {
+			sprite.Draw(b.batch, pixel.IM.Scaled(pixel.ZV, scaleFactor).Moved(screenLayout.cell(p.row, p.col)))
 		}
 	}
+
+	b.batch.Draw(win)
+
+	b.displayGarbageMeter(win)
 }
 
 // block2spriteIdx associates a blocks color (b Block) with its index in the sprite sheet.
@@ -281,131 +416,143 @@ func block2spriteIdx(b Block) int {
 	return int(b) - 1
 }
 
-// piece2Block associates a pieces shape (Piece) with it's color/image (Block).
-func piece2Block(p Piece) Block {
-	switch p {
-	case LPiece:
-		return Goluboy
-	case IPiece:
-		return Siniy
-	case OPiece:
-		return Pink
-	case TPiece:
-		return Purple
-	case SPiece:
-		return Red
-	case ZPiece:
-		return Yellow
-	case JPiece:
-		return Green
-	}
-	panic(any("piece2Block: Invalid piece passed in"))
-	return GraySpecial // Return strange value value
+func (b *Board) displayPaused(win pixel.Target) {
+	b.displayMessage(win, "Game Pause")
 }
 
-func (b *Board) displayPaused(win *pixelgl.Window) {
-	// Text Generator
-	scoreTextLocX := 315.0
-	scoreTextLocY := 215.0
-	basicAtlas := text.NewAtlas(basicfont.Face7x13, text.ASCII)
-	scoreTxt := text.New(pixel.V(scoreTextLocX, scoreTextLocY), basicAtlas)
-	fmt.Fprintf(scoreTxt, "Game Pause")
+// displayMessage writes msg across the middle of the playing field.
+func (b *Board) displayMessage(win pixel.Target, msg string) {
+	scoreTxt := b.newText(screenLayout.message)
+	fmt.Fprint(scoreTxt, b.lang.trLines(msg))
 	scoreTxt.Draw(win, pixel.IM.Scaled(scoreTxt.Orig, 2))
 }
 
-func (b *Board) displayText(win *pixelgl.Window) {
-	// 玩法说明
-	b.displayIntroduction(win)
+func (b *Board) displayText(win pixel.Target, help string) {
+	// How to play
+	b.displayIntroduction(win, help)
 
-	// 分数
-	scoreTextLocX := 100.0
-	scoreTextLocY := 400.0
-	basicAtlas := text.NewAtlas(basicfont.Face7x13, text.ASCII)
-	scoreTxt := text.New(pixel.V(scoreTextLocX, scoreTextLocY), basicAtlas)
-	fmt.Fprintf(scoreTxt, "Score")
-	scoreTxt.Draw(win, pixel.IM.Scaled(scoreTxt.Orig, 2))
+	// Score
+	scoreTextLocX := screenLayout.score.X
+	scoreTextLocY := screenLayout.score.Y
+	scoreOrig := pixel.V(scoreTextLocX, scoreTextLocY)
+	scoreTxt := b.newText(scoreOrig)
+	fmt.Fprint(scoreTxt, b.lang.tr("Score"))
+	scoreTxt.Draw(win, pixel.IM.Scaled(scoreOrig, 2))
 
-	score := text.New(pixel.V(scoreTextLocX, scoreTextLocY-30), basicAtlas)
+	score := b.newText(pixel.V(scoreTextLocX, scoreTextLocY-30))
 	fmt.Fprintf(score, "%d", b.score)
-	score.Draw(win, pixel.IM.Scaled(scoreTxt.Orig, 1.5))
+	score.Draw(win, pixel.IM.Scaled(scoreOrig, 1.5))
 
-	nextPieceTextLocX := 100.0
-	nextPieceTextLocY := 300.0
-	nextPieceTxt := text.New(pixel.V(nextPieceTextLocX, nextPieceTextLocY), basicAtlas)
+	nextPieceTxt := b.newText(screenLayout.nextLabel)
 
-	fmt.Fprintf(nextPieceTxt, "Next Piece")
-	nextPieceTxt.Draw(win, pixel.IM.Scaled(scoreTxt.Orig, 2))
+	fmt.Fprint(nextPieceTxt, b.lang.tr("Next Piece"))
+	nextPieceTxt.Draw(win, pixel.IM.Scaled(scoreOrig, 2))
 }
 
-func (b *Board) displayIntroduction(win *pixelgl.Window) {
-	scoreTextLocX := 460.0
-	scoreTextLocY := 350.0
-	basicAtlas := text.NewAtlas(basicfont.Face7x13, text.ASCII)
-	scoreTxt := text.New(pixel.V(scoreTextLocX, scoreTextLocY), basicAtlas)
-	fmt.Fprintf(scoreTxt, `
-	L/R arrow - Move block
-
-	Up arrow - Rotate block
-
-	Down arrow - Fast fall
-
-	Space - Instant drop
-
-	Clike - Pause
-	`)
-	scoreTxt.Draw(win, pixel.IM.Scaled(scoreTxt.Orig, 1.5))
+// displayIntroduction writes help beside the playing field, shrinking it if
+// it has too many lines to fit above the bottom of the window.
+func (b *Board) displayIntroduction(win pixel.Target, help string) {
+	scoreTxt := b.newText(screenLayout.help)
+	fmt.Fprint(scoreTxt, b.lang.trLines(help))
+	scale := 1.5
+	lines := float64(strings.Count(help, "\n") + 1)
+	if room := screenLayout.help.Y - screenLayout.field.Y; lines*scoreTxt.LineHeight*scale > room {
+		scale = room / (lines * scoreTxt.LineHeight)
+	}
+	scoreTxt.Draw(win, pixel.IM.Scaled(scoreTxt.Orig, scale))
 }
 
-func (b *Board) displayBG(win *pixelgl.Window) {
-	b.bgImgSprite.Draw(win, pixel.IM.Moved(win.Bounds().Center()))
-	b.gameBGSprite.Draw(win, pixel.IM.Moved(win.Bounds().Center()))
-	b.scoreBgSprite.Draw(win, pixel.IM.Moved(pixel.V(200, 360)))
-	b.nextPieceBGSprite.Draw(win, pixel.IM.Moved(pixel.V(150, 120)))
-
-	baseShape := getShapeFromPiece(b.nextPiece)
-	pic := b.blockGen(block2spriteIdx(piece2Block(b.nextPiece)))
-	sprite := pixel.NewSprite(pic, pic.Bounds())
-	boardBlockSize := 20.0
-	scaleFactor := float64(boardBlockSize) / pic.Bounds().Max.Y
-	shapeWidth := getShapeWidth(baseShape) + 1
-	shapeHeight := 2
+func (b *Board) displayBG(win pixel.Target) {
+	b.skin.background.Draw(win, pixel.IM.Moved(pixel.V(screenLayout.width/2, screenLayout.height/2)))
+	b.skin.field.Draw(win, pixel.IM.Moved(screenLayout.fieldRect().Center()))
+	b.skin.scoreBox.Draw(win, pixel.IM.Moved(screenLayout.scoreBox))
+	b.skin.nextBox.Draw(win, pixel.IM.Moved(screenLayout.nextBox))
+
+	b.batch.Clear()
+	b.drawPreview(b.nextPiece, screenLayout.nextBox, screenLayout.block, 4.5)
+
+	// The rest of the preview goes at half size in a column beside the
+	// playing field
+	small := screenLayout.block / 2
+	for i, p := range b.preview {
+		center := screenLayout.preview.Sub(pixel.V(0, float64(i)*small*4.5-small))
+		b.drawPreview(p, center, small, 4)
+	}
+	b.batch.Draw(win)
+}
 
-	for i := 0; i < 4; i++ {
-		r := baseShape[i].row
-		c := baseShape[i].col
-		x := float64(c)*boardBlockSize + boardBlockSize/2
-		y := float64(r)*boardBlockSize + boardBlockSize/2
-		sprite.Draw(win, pixel.IM.Scaled(pixel.ZV, scaleFactor).Moved(pixel.V(x+150-(float64(shapeWidth)*10), y+120-(float64(shapeHeight)*10))))
+// drawPreview adds piece p, as it spawns, to the batch centred on center in
+// blocks of the given size. Pieces more than room blocks across are shrunk to
+// fit.
+func (b *Board) drawPreview(p Piece, center pixel.Vec, size, room float64) {
+	shape := b.set.shape(p)
+	minRow, maxRow, minCol, maxCol := shapeBounds(shape)
+	w, h := float64(maxCol-minCol+1), float64(maxRow-minRow+1)
+	if span := math.Max(w, h); span > room {
+		size *= room / span
+	}
+	sprite := b.skin.block(b.pieceBlock(p))
+	scaleFactor := b.skin.scale * size / screenLayout.block
+	for _, pt := range shape {
+		x := center.X + (float64(pt.col-minCol)+0.5-w/2)*size
+		y := center.Y + (float64(pt.row-minRow)+0.5-h/2)*size
+		sprite.Draw(b.batch, pixel.IM.Scaled(pixel.ZV, scaleFactor).Moved(pixel.V(x, y)))
 	}
 }
 
 func (b *Board) initResource() {
-	var err error
-	// Load Various Resources:
-	b.blockGen, err = spritesheet.InitBlock("blocks.png", 2, 8)
-	if err != nil {
-		panic(any(err))
-	}
+	b.useTheme(currentTheme())
+}
 
-	bgPic, err := spritesheet.LoadPicture("bg_whitecanvas.png")
-	if err != nil {
-		panic(any(err))
-	}
-	b.bgImgSprite = *pixel.NewSprite(bgPic, bgPic.Bounds())
+// useSettings prepares the board to be drawn styled and worded as s asks.
+func (b *Board) useSettings(s Settings) {
+	b.useTheme(s.theme(b.set))
+	b.lang = s.Language
+	b.ghost = s.Ghost
+	b.ghostColor, b.customGhost = s.ghostColor()
+}
 
-	// tetrisGame Background
-	blackPic := spritesheet.GetPlayBGPic()
-	b.gameBGSprite = *pixel.NewSprite(blackPic, blackPic.Bounds())
+// useTheme prepares the board to be drawn with t.
+func (b *Board) useTheme(t *spritesheet.Theme) {
+	b.skin = skinFor(t)
+	b.batch = pixel.NewBatch(&pixel.TrianglesData{}, t.Sheet)
+	b.txt = text.New(pixel.ZV, b.skin.atlas)
+	b.txt.Color = t.Text
+	b.imd = imdraw.New(nil)
+}
 
-	// Score BG
-	scoreBgPic := spritesheet.GetScoreBGPic()
-	b.scoreBgSprite = *pixel.NewSprite(scoreBgPic, scoreBgPic.Bounds())
+// newText returns the text of the board, emptied and moved to orig, to
+// write at orig in the font and colour of the theme. The text is shared, so
+// it has to be drawn before newText is called again.
+func (b *Board) newText(orig pixel.Vec) *text.Text {
+	b.txt.Orig = orig
+	b.txt.Clear()
+	return b.txt
+}
 
-	// Next Piece BG
-	nextPiecePic := spritesheet.GetNextPieceBGPic()
-	b.nextPieceBGSprite = *pixel.NewSprite(nextPiecePic, nextPiecePic.Bounds())
+// newShapes returns the shape drawer of the board, emptied and reset. It is
+// shared like the text of newText.
+func (b *Board) newShapes() *imdraw.IMDraw {
+	b.imd.Clear()
+	b.imd.Reset()
+	return b.imd
 }
 
 func (b *Board) GameOver() bool {
 	return b.gameOver
 }
+
+// Score returns the score of the board.
+func (b *Board) Score() int {
+	return b.score
+}
+
+// Lines returns the number of rows cleared over the whole game.
+func (b *Board) Lines() int {
+	return b.lines
+}
+
+// Pieces returns the number of pieces spawned over the whole game.
+func (b *Board) Pieces() int {
+	return b.pieces
+}
@@ -0,0 +1,435 @@
+package tetris
+
+import (
+	"strings"
+	"testing"
+)
+
+// emptyBoard returns a board of tetrominoes with nothing on it and no piece
+// spawned yet.
+func emptyBoard() *Board {
+	return NewSeededBoard(1)
+}
+
+// fillRows sets the locked cells of b from rows drawn top first, with # for
+// a Gray block and . for an empty cell. The last string is row 0.
+func fillRows(b *Board, rows ...string) {
+	for i, line := range rows {
+		r := len(rows) - 1 - i
+		for c, ch := range line {
+			if ch == '#' {
+				b.board[r][c] = Gray
+			} else {
+				b.board[r][c] = Empty
+			}
+		}
+	}
+}
+
+// place makes p the active piece in rotation state, with the lowest of its
+// cells on row and the leftmost on col.
+func place(b *Board, p Piece, state, row, col int) {
+	b.currentPiece = p
+	minRow, _, minCol, _ := shapeBounds(b.set.def(p).States[state])
+	b.setActive(pose{state: state, row: row - minRow, col: col - minCol})
+}
+
+// recordEvents returns the events b publishes from now on.
+func recordEvents(b *Board) *[]Event {
+	var events []Event
+	if b.events == nil {
+		b.events = &EventBus{}
+	}
+	b.events.Subscribe(func(ev Event) { events = append(events, ev) })
+	return &events
+}
+
+func TestCheckCollision(t *testing.T) {
+	b := emptyBoard()
+	fillRows(b, "#.........")
+	tests := []struct {
+		name string
+		s    Shape
+		want bool
+	}{
+		{"inside", Shape{{5, 5}, {5, 6}}, false},
+		{"left of the board", Shape{{5, -1}}, true},
+		{"right of the board", Shape{{5, BoardCols}}, true},
+		{"below the floor", Shape{{-1, 3}}, true},
+		{"above the hidden rows", Shape{{BoardRows, 3}}, true},
+		{"in the hidden rows", Shape{{BoardRows - 1, 3}}, false},
+		{"on a locked block", Shape{{1, 1}, {0, 0}}, true},
+		{"next to a locked block", Shape{{0, 1}}, false},
+	}
+	for _, tt := range tests {
+		if got := b.checkCollision(tt.s); got != tt.want {
+			t.Errorf("%s: checkCollision = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestActivePieceIsNotInTheGrid(t *testing.T) {
+	b := emptyBoard()
+	b.AddPiece()
+	for i := 0; i < 5; i++ {
+		b.Apply(Gravity)
+		b.Apply(Rotate)
+	}
+	if b.board != ([BoardRows][BoardCols]Block{}) {
+		t.Errorf("the falling piece left blocks in the grid:\n%s", boardText(b))
+	}
+	if b.isTouchingFloor() {
+		t.Error("the piece touches the floor in the middle of an empty board")
+	}
+}
+
+func TestSpawn(t *testing.T) {
+	for _, name := range BuiltinPieceSets() {
+		set, err := LookupPieceSet(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := int64(0); i < 50; i++ {
+			b := newRuledBoard(i, Guideline, 1, set)
+			b.AddPiece()
+			def := b.active()
+			if b.activePose.state != 0 || b.activePose.row != def.Spawn {
+				t.Errorf("%s %s: spawned in pose %+v, want state 0 on row %d", name, def.Name, b.activePose, def.Spawn)
+			}
+			if b.checkCollision(b.activeShape) {
+				t.Errorf("%s %s: spawned outside of the board at %v", name, def.Name, b.activeShape)
+			}
+			if b.GameOver() {
+				t.Errorf("%s %s: game over after spawning on an empty board", name, def.Name)
+			}
+		}
+	}
+}
+
+func TestSpawnIntoTheStackEndsTheGame(t *testing.T) {
+	b := emptyBoard()
+	events := recordEvents(b)
+	for r := 0; r < BoardRows; r++ {
+		for c := 0; c < BoardCols; c++ {
+			if c != r%BoardCols {
+				b.board[r][c] = Gray
+			}
+		}
+	}
+	before := b.board
+	b.AddPiece()
+	if !b.GameOver() {
+		t.Fatal("a piece spawned into the stack without ending the game")
+	}
+	if b.board != before {
+		t.Error("spawning overwrote the stack")
+	}
+	if _, ok := (*events)[len(*events)-1].(GameEnded); !ok {
+		t.Errorf("last event = %T, want GameEnded", (*events)[len(*events)-1])
+	}
+}
+
+func TestMoveStopsAtWalls(t *testing.T) {
+	b := emptyBoard()
+	place(b, OPiece, 0, 10, 0)
+	b.Apply(MoveLeft)
+	if got := b.activeShape; !sameCells(got, Shape{{10, 0}, {10, 1}, {11, 0}, {11, 1}}) {
+		t.Errorf("moved into the left wall: %v", got)
+	}
+	for i := 0; i < BoardCols; i++ {
+		b.Apply(MoveRight)
+	}
+	if _, _, _, maxCol := shapeBounds(b.activeShape); maxCol != BoardCols-1 {
+		t.Errorf("rightmost column = %d after moving right, want %d", maxCol, BoardCols-1)
+	}
+}
+
+func TestRotationStates(t *testing.T) {
+	b := emptyBoard()
+	place(b, TPiece, 0, 10, 4)
+	start := b.activePose
+	for _, tt := range []struct {
+		a     Action
+		state int
+	}{
+		{Rotate, 1}, {Rotate, 2}, {RotateCCW, 1}, {Rotate180, 3}, {Rotate180, 1}, {RotateCCW, 0},
+	} {
+		b.Apply(tt.a)
+		if b.activePose.state != tt.state {
+			t.Fatalf("state = %d after %v, want %d", b.activePose.state, tt.a, tt.state)
+		}
+	}
+	if b.activePose != start {
+		t.Errorf("pose = %+v after turning a full circle, want %+v", b.activePose, start)
+	}
+}
+
+func TestRotationKicks(t *testing.T) {
+	tests := []struct {
+		name        string
+		piece       Piece
+		state       int
+		col         int
+		a           Action
+		stack       []string
+		wantOffset  Point // Kick the rotation should take, from the unkicked pose
+		wantRotated bool
+	}{
+		// The vertical I is in column 1 of its box, so turning it at the
+		// walls would stick out of the board
+		{"I off the left wall", IPiece, 3, 0, Rotate, nil, Point{0, 1}, true},
+		{"I off the right wall", IPiece, 1, BoardCols - 1, Rotate, nil, Point{0, -1}, true},
+		{"T in the open", TPiece, 0, 4, Rotate, nil, Point{0, 0}, true},
+		{"O does not turn", OPiece, 0, 4, Rotate, nil, Point{0, 0}, false},
+		{"T half turn on the floor", TPiece, 2, 4, Rotate180, nil, Point{1, 0}, true},
+		{"J boxed in", JPiece, 0, 1, Rotate, []string{
+			"#...######",
+			"#...######",
+		}, Point{0, 0}, false},
+	}
+	for _, tt := range tests {
+		b := emptyBoard()
+		fillRows(b, tt.stack...)
+		row := 0
+		if tt.stack == nil && tt.a != Rotate180 {
+			row = 10
+		}
+		place(b, tt.piece, tt.state, row, tt.col)
+		before := b.activePose
+		b.Apply(tt.a)
+		if rotated := b.activePose != before; rotated != tt.wantRotated {
+			t.Errorf("%s: rotated = %v, want %v", tt.name, rotated, tt.wantRotated)
+			continue
+		}
+		if !tt.wantRotated {
+			continue
+		}
+		if got := (Point{b.activePose.row - before.row, b.activePose.col - before.col}); got != tt.wantOffset {
+			t.Errorf("%s: kicked by %v, want %v", tt.name, got, tt.wantOffset)
+		}
+		if b.checkCollision(b.activeShape) {
+			t.Errorf("%s: rotated into %v, which collides", tt.name, b.activeShape)
+		}
+	}
+}
+
+func TestLineClears(t *testing.T) {
+	for n := 1; n <= 4; n++ {
+		b := emptyBoard()
+		var stack []string
+		for i := 0; i < n; i++ {
+			stack = append(stack, "#########.")
+		}
+		fillRows(b, stack...)
+		place(b, IPiece, 1, 10, BoardCols-1)
+		events := recordEvents(b)
+		b.Apply(HardDrop)
+
+		var locked PieceLocked
+		for _, ev := range *events {
+			if ev, ok := ev.(PieceLocked); ok {
+				locked = ev
+			}
+		}
+		if locked.Cleared != n || b.Lines() != n {
+			t.Errorf("%d rows: cleared %d, lines %d", n, locked.Cleared, b.Lines())
+		}
+		// What is left is the part of the I above the cleared rows
+		for r := 0; r < BoardRows; r++ {
+			for c := 0; c < BoardCols; c++ {
+				want := Empty
+				if c == BoardCols-1 && r < 4-n {
+					want = b.pieceBlock(IPiece)
+				}
+				if b.board[r][c] != want {
+					t.Fatalf("%d rows: cell %d,%d = %v, want %v\n%s", n, r, c, b.board[r][c], want, boardText(b))
+				}
+			}
+		}
+	}
+}
+
+func TestClearingTheTopRow(t *testing.T) {
+	b := emptyBoard()
+	fillRows(b, strings.Repeat("#", BoardCols), strings.Repeat("#", BoardCols))
+	b.board[BoardRows-1] = b.board[0]
+	if n := b.checkRowCompletion(Shape{{BoardRows - 1, 0}, {0, 0}}); n != 3 {
+		t.Errorf("cleared %d rows, want 3", n)
+	}
+	if b.board != ([BoardRows][BoardCols]Block{}) {
+		t.Errorf("rows left after clearing every full one:\n%s", boardText(b))
+	}
+}
+
+func TestScoring(t *testing.T) {
+	// Locking by gravity earns 10, a hard drop 12, and each piece earns
+	// 200 a row plus 200 for each row beyond the first
+	tests := []struct {
+		rows int
+		drop Action
+		want int
+	}{
+		{0, Gravity, 10},
+		{0, HardDrop, 12},
+		{1, HardDrop, 12 + 200},
+		{2, HardDrop, 12 + 600},
+		{3, HardDrop, 12 + 1000},
+		{4, HardDrop, 12 + 1400},
+		{4, Gravity, 10 + 1400},
+	}
+	for _, tt := range tests {
+		b := emptyBoard()
+		var stack []string
+		for i := 0; i < tt.rows; i++ {
+			stack = append(stack, "#########.")
+		}
+		fillRows(b, stack...)
+		place(b, IPiece, 1, 0, BoardCols-1)
+		if !b.Apply(tt.drop) {
+			t.Fatalf("%v on the floor did not lock", tt.drop)
+		}
+		if b.Score() != tt.want {
+			t.Errorf("%d rows by %v: score %d, want %d", tt.rows, tt.drop, b.Score(), tt.want)
+		}
+	}
+}
+
+func TestAttack(t *testing.T) {
+	for rows, want := range attackTable {
+		b := emptyBoard()
+		var stack []string
+		for i := 0; i < rows; i++ {
+			stack = append(stack, "#########.")
+		}
+		fillRows(b, stack...)
+		place(b, IPiece, 1, 0, BoardCols-1)
+		b.Apply(HardDrop)
+		if got := b.TakeAttack(); got != want {
+			t.Errorf("%d rows: attack %d, want %d", rows, got, want)
+		}
+	}
+}
+
+func TestGarbage(t *testing.T) {
+	b := emptyBoard()
+	b.QueueGarbage(2)
+	b.QueueGarbage(3)
+
+	// A double cancels one of the five rows waiting
+	fillRows(b, "#########.", "#########.")
+	place(b, IPiece, 1, 0, BoardCols-1)
+	b.Apply(HardDrop)
+	if got := b.PendingGarbage(); got != 4 {
+		t.Fatalf("pending garbage = %d after a double, want 4", got)
+	}
+	if got := b.TakeAttack(); got != 0 {
+		t.Errorf("attack = %d, want it all spent cancelling", got)
+	}
+
+	// Locking without clearing raises the rest
+	place(b, OPiece, 0, 15, 0)
+	b.Apply(HardDrop)
+	if got := b.PendingGarbage(); got != 0 {
+		t.Errorf("pending garbage = %d after it rose, want 0", got)
+	}
+	for r := 0; r < 4; r++ {
+		holes := 0
+		for c := 0; c < BoardCols; c++ {
+			if b.board[r][c] == Empty {
+				holes++
+			} else if b.board[r][c] != Gray {
+				t.Fatalf("row %d has %v in it, want only garbage\n%s", r, b.board[r][c], boardText(b))
+			}
+		}
+		if holes != 1 {
+			t.Errorf("garbage row %d has %d holes, want 1", r, holes)
+		}
+	}
+}
+
+func TestGameOver(t *testing.T) {
+	t.Run("locking in the hidden rows", func(t *testing.T) {
+		b := emptyBoard()
+		for r := 0; r < BoardRows-2; r++ {
+			b.board[r][0] = Gray
+		}
+		place(b, OPiece, 0, BoardRows-2, 0)
+		b.Apply(HardDrop)
+		if !b.GameOver() {
+			t.Errorf("no game over after locking in the hidden rows\n%s", boardText(b))
+		}
+	})
+	t.Run("locking on the last visible row", func(t *testing.T) {
+		b := emptyBoard()
+		for r := 0; r < BoardRows-4; r++ {
+			b.board[r][0] = Gray
+		}
+		place(b, OPiece, 0, BoardRows-2, 0)
+		b.Apply(HardDrop)
+		if b.GameOver() {
+			t.Errorf("game over after locking below the hidden rows\n%s", boardText(b))
+		}
+	})
+	t.Run("garbage pushing blocks out", func(t *testing.T) {
+		b := emptyBoard()
+		for r := 0; r < BoardRows-2; r++ {
+			b.board[r][0] = Gray
+		}
+		b.raiseGarbage(1, 5)
+		if !b.GameOver() {
+			t.Errorf("no game over after garbage pushed blocks into the hidden rows\n%s", boardText(b))
+		}
+	})
+	t.Run("ended once", func(t *testing.T) {
+		b := emptyBoard()
+		events := recordEvents(b)
+		b.endGame()
+		b.endGame()
+		ended := 0
+		for _, ev := range *events {
+			if _, ok := ev.(GameEnded); ok {
+				ended++
+			}
+		}
+		if ended != 1 {
+			t.Errorf("GameEnded published %d times, want once", ended)
+		}
+	})
+}
+
+func TestTSpin(t *testing.T) {
+	b := emptyBoard()
+	fillRows(b,
+		"##........",
+		"#...######",
+		"##.#######",
+	)
+	place(b, TPiece, 0, 0, 1)
+	b.lastRotated = true
+	if !b.isTSpin() {
+		t.Fatalf("not a T-spin:\n%s", boardText(b))
+	}
+	events := recordEvents(b)
+	b.Apply(HardDrop)
+	cleared := false
+	for _, ev := range *events {
+		if ev, ok := ev.(LinesCleared); ok {
+			cleared = ev.TSpin && ev.Rows == 2
+		}
+	}
+	if !cleared {
+		t.Errorf("no T-spin double cleared:\n%s", boardText(b))
+	}
+
+	b = emptyBoard()
+	fillRows(b, "##........", "#...######", "##.#######")
+	place(b, TPiece, 0, 0, 1)
+	if b.isTSpin() {
+		t.Error("a T that was not rotated counted as a T-spin")
+	}
+}
+
+// sameCells reports whether a and b cover the same cells.
+func sameCells(a, b Shape) bool {
+	return shapeKey(a) == shapeKey(b)
+}
@@ -0,0 +1,336 @@
+package tetris
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// botDelay is the time between the actions of a bot playing in the window,
+// so that it can be followed by eye.
+const botDelay = 0.05
+
+// BotState is what a Bot is shown of the game when a new piece appears.
+type BotState struct {
+	Cells  [BoardRows][BoardCols]Block // Locked blocks, without the active piece
+	Piece  Piece                       // The active piece
+	Shape  Shape                       // Where the active piece is now
+	Next   []Piece                     // The pieces coming after it, next first
+	Pieces *PieceSet                   // The set Piece and Next are from
+
+	pose pose // Where Shape is, in the PieceDef of Piece
+}
+
+// Bot is an automated player. Each time a new piece appears it is asked
+// how to place it and the actions it returns are applied in order. If they
+// do not lock the piece, gravity eventually will.
+type Bot interface {
+	Play(s BotState) []Action
+}
+
+// botState returns the state shown to bots.
+func (b *Board) botState() BotState {
+	return BotState{
+		Cells:  b.board,
+		Piece:  b.currentPiece,
+		Shape:  b.activeShape,
+		Next:   append([]Piece{b.nextPiece}, b.preview...),
+		Pieces: b.set,
+		pose:   b.activePose,
+	}
+}
+
+// board returns a board holding the state, with the active piece where the
+// state has it, for bots to try moves on. It has no piece generator and must not be
+// used to spawn pieces.
+func (s BotState) board() *Board {
+	return &Board{board: s.Cells, currentPiece: s.Piece, activeShape: s.Shape, activePose: s.pose, set: s.Pieces}
+}
+
+// Placement is a position a piece can be locked in, together with the actions
+// that take it there.
+type Placement struct {
+	Shape   Shape
+	Actions []Action
+}
+
+// Weights are how much each feature of a board counts towards the
+// HeuristicBot's evaluation of a placement. Features are described in
+// evaluate.
+type Weights struct {
+	LandingHeight     float64
+	ErodedCells       float64
+	RowTransitions    float64
+	ColumnTransitions float64
+	Holes             float64
+	Wells             float64
+	AggregateHeight   float64
+	Bumpiness         float64
+}
+
+// ElTetrisWeights are the weights found for Dellacherie's features by the
+// El-Tetris project.
+var ElTetrisWeights = Weights{
+	LandingHeight:     -4.500158825082766,
+	ErodedCells:       3.4181268101392694,
+	RowTransitions:    -3.2178882868487753,
+	ColumnTransitions: -9.348695305445199,
+	Holes:             -7.899265427351652,
+	Wells:             -3.3855972247263626,
+}
+
+// HeuristicBot places each piece in whichever reachable position scores best
+// according to its Weights, looking only at the current piece.
+type HeuristicBot struct {
+	Weights Weights
+}
+
+func NewHeuristicBot() *HeuristicBot {
+	return &HeuristicBot{Weights: ElTetrisWeights}
+}
+
+func (h *HeuristicBot) Play(s BotState) []Action {
+	p, _ := h.Weights.best(s)
+	return p.Actions
+}
+
+// best returns the placement of the active piece that scores highest.
+// Returns false if the piece cannot be placed anywhere.
+func (w Weights) best(s BotState) (Placement, bool) {
+	bestScore := math.Inf(-1)
+	var best Placement
+	found := false
+	for _, p := range s.board().Placements() {
+		if v := w.evaluate(s.Cells, p.Shape); v > bestScore {
+			bestScore = v
+			best = p
+			found = true
+		}
+	}
+	return best, found
+}
+
+// evaluate scores the board, cells, after a piece is locked in shape s.
+func (w Weights) evaluate(cells [BoardRows][BoardCols]Block, s Shape) float64 {
+	minRow, maxRow := BoardRows, -1
+	for _, p := range s {
+		cells[p.row][p.col] = Gray
+		if p.row < minRow {
+			minRow = p.row
+		}
+		if p.row > maxRow {
+			maxRow = p.row
+		}
+	}
+
+	// Clear full rows, counting the cells of the piece that went with them
+	var cleared, pieceCells int
+	kept := 0
+	for r := 0; r < BoardRows; r++ {
+		full := true
+		for c := 0; c < BoardCols; c++ {
+			if cells[r][c] == Empty {
+				full = false
+				break
+			}
+		}
+		if full {
+			cleared++
+			for _, p := range s {
+				if p.row == r {
+					pieceCells++
+				}
+			}
+			continue
+		}
+		cells[kept] = cells[r]
+		kept++
+	}
+	for r := kept; r < BoardRows; r++ {
+		cells[r] = [BoardCols]Block{}
+	}
+
+	landingHeight := float64(minRow+maxRow)/2 + 1
+	erodedCells := float64(cleared * pieceCells)
+
+	// Row transitions: changes between filled and empty along each row,
+	// with the walls counting as filled
+	var rowTransitions int
+	for r := 0; r < BoardRows; r++ {
+		filled := true
+		for c := 0; c <= BoardCols; c++ {
+			f := c == BoardCols || cells[r][c] != Empty
+			if f != filled {
+				rowTransitions++
+			}
+			filled = f
+		}
+	}
+
+	// Column transitions, holes and heights, with the floor counting as
+	// filled
+	var colTransitions, holes, aggregateHeight, bumpiness int
+	var heights [BoardCols]int
+	for c := 0; c < BoardCols; c++ {
+		filled := true
+		for r := 0; r < BoardRows; r++ {
+			f := cells[r][c] != Empty
+			if f != filled {
+				colTransitions++
+			}
+			if f {
+				heights[c] = r + 1
+			}
+			filled = f
+		}
+		for r := 0; r < heights[c]; r++ {
+			if cells[r][c] == Empty {
+				holes++
+			}
+		}
+		aggregateHeight += heights[c]
+		if c > 0 && heights[c] > heights[c-1] {
+			bumpiness += heights[c] - heights[c-1]
+		} else if c > 0 {
+			bumpiness += heights[c-1] - heights[c]
+		}
+	}
+
+	// Wells: empty cells with both neighbours filled, where deeper wells
+	// count for more (1 + 2 + ... + depth)
+	var wells int
+	for c := 0; c < BoardCols; c++ {
+		depth := 0
+		for r := BoardRows - 1; r >= 0; r-- {
+			left := c == 0 || cells[r][c-1] != Empty
+			right := c == BoardCols-1 || cells[r][c+1] != Empty
+			if cells[r][c] == Empty && left && right {
+				depth++
+				wells += depth
+			} else {
+				depth = 0
+			}
+		}
+	}
+
+	return w.LandingHeight*landingHeight +
+		w.ErodedCells*erodedCells +
+		w.RowTransitions*float64(rowTransitions) +
+		w.ColumnTransitions*float64(colTransitions) +
+		w.Holes*float64(holes) +
+		w.Wells*float64(wells) +
+		w.AggregateHeight*float64(aggregateHeight) +
+		w.Bumpiness*float64(bumpiness)
+}
+
+// playBot lets the player's bot take its next action once botDelay has
+// passed since the last one.
+func (p *player) playBot(dt float64) {
+	p.botTimer += dt
+	if p.botTimer < botDelay {
+		return
+	}
+	p.botTimer = 0
+	if !p.botPlanned {
+		p.botPlan = p.bot.Play(p.board.botState())
+		p.botPlanned = true
+	}
+	if len(p.botPlan) == 0 {
+		return
+	}
+	a := p.botPlan[0]
+	p.botPlan = p.botPlan[1:]
+	p.apply(a)
+}
+
+// BotReport sums up the games played by BenchBot.
+type BotReport struct {
+	Games     int
+	TopOuts   int // Games that ended before reaching the piece limit
+	Pieces    int
+	Lines     int
+	MinScore  int
+	MaxScore  int
+	MeanScore float64
+	Elapsed   time.Duration
+}
+
+func (r BotReport) String() string {
+	return fmt.Sprintf("%d games (%d topped out) in %v: %d pieces, %d lines, score min %d / mean %.1f / max %d",
+		r.Games, r.TopOuts, r.Elapsed.Round(time.Millisecond), r.Pieces, r.Lines, r.MinScore, r.MeanScore, r.MaxScore)
+}
+
+// BenchBot plays games without a window, the i-th game on a board seeded
+// with seed+i, each until it tops out or maxPieces have been placed. Games
+// are spread over every CPU, with a bot made by newBot for each one.
+func BenchBot(newBot func() Bot, games int, seed int64, maxPieces int) BotReport {
+	start := time.Now()
+	type result struct {
+		score, pieces, lines int
+		toppedOut            bool
+	}
+	results := make([]result, games)
+
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				b := NewSeededBoard(seed + int64(i))
+				b.AddPiece()
+				pieces := playHeadless(b, newBot(), maxPieces)
+				results[i] = result{score: b.score, pieces: pieces, lines: b.lines, toppedOut: b.GameOver()}
+			}
+		}()
+	}
+	for i := 0; i < games; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	r := BotReport{Games: games, MinScore: math.MaxInt, Elapsed: time.Since(start)}
+	for _, res := range results {
+		if res.toppedOut {
+			r.TopOuts++
+		}
+		r.Pieces += res.pieces
+		r.Lines += res.lines
+		if res.score < r.MinScore {
+			r.MinScore = res.score
+		}
+		if res.score > r.MaxScore {
+			r.MaxScore = res.score
+		}
+		r.MeanScore += float64(res.score)
+	}
+	if games > 0 {
+		r.MeanScore /= float64(games)
+	} else {
+		r.MinScore = 0
+	}
+	return r
+}
+
+// playHeadless lets bot place pieces on b until it tops out or maxPieces
+// have been placed. Returns the number of pieces placed.
+func playHeadless(b *Board, bot Bot, maxPieces int) int {
+	pieces := 0
+	for pieces < maxPieces && !b.GameOver() {
+		locked := false
+		for _, a := range bot.Play(b.botState()) {
+			if locked = b.Apply(a); locked {
+				break
+			}
+		}
+		if !locked {
+			b.Apply(HardDrop)
+		}
+		pieces++
+	}
+	return pieces
+}
@@ -0,0 +1,87 @@
+//go:build !js
+
+package tetris
+
+import (
+	"image"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/faiface/pixel/pixelgl"
+)
+
+// clipSeconds is how much of the game before the key is pressed goes into
+// a GIF saved while playing.
+const clipSeconds = 10
+
+// windowImage returns what was last drawn in win.
+func windowImage(win *pixelgl.Window) *image.RGBA {
+	canvas := win.Canvas()
+	w, h := canvas.Texture().Width(), canvas.Texture().Height()
+	pixels := canvas.Pixels()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	// The canvas has its first row at the bottom
+	for y := 0; y < h; y++ {
+		copy(img.Pix[y*img.Stride:(y+1)*img.Stride], pixels[(h-1-y)*w*4:(h-y)*w*4])
+	}
+	return img
+}
+
+// screenshot saves what the window shows as a PNG.
+func (g *tetrisGame) screenshot() {
+	path, err := capturePath(time.Now(), ".png")
+	if err == nil {
+		err = writePNG(path, windowImage(g.win))
+	}
+	if err != nil {
+		log.Printf("saving screenshot: %v", err)
+		return
+	}
+	log.Printf("saved screenshot to %s", path)
+}
+
+func writePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// saveClip saves the last clipSeconds of play as a GIF, drawn as the
+// settings ask. It plays the game again from its replay, so it cannot be
+// done once the replay has been given up on.
+func (g *tetrisGame) saveClip() {
+	r := g.recording
+	if g.replay != nil {
+		r = &Replay{Version: replayVersion, Config: g.replay.Config, Actions: g.replay.Actions[:g.replayNext]}
+	}
+	if r == nil {
+		log.Printf("not saving a GIF: the game cannot be played back")
+		return
+	}
+	now := g.player.stats.Seconds
+	o := GIFOptions{From: now - clipSeconds, To: now, Settings: g.settings}
+	if o.From < 0 {
+		o.From = 0
+	}
+	path, err := capturePath(time.Now(), ".gif")
+	if err == nil {
+		err = r.SaveGIF(path, o)
+	}
+	if err != nil {
+		log.Printf("saving GIF: %v", err)
+		return
+	}
+	log.Printf("saved GIF to %s", path)
+}
@@ -0,0 +1,196 @@
+package tetris
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Mode is what the player has to do to finish a game.
+type Mode string
+
+// Various game modes
+const (
+	Marathon Mode = "marathon" // Play until topping out
+	Sprint   Mode = "sprint"   // Clear sprintLines rows as fast as possible
+)
+
+// sprintLines is how many rows a sprint is won by clearing.
+const sprintLines = 40
+
+// Ruleset is the set of rules a game is played by.
+type Ruleset string
+
+// Various rulesets
+const (
+	Classic   Ruleset = "classic"   // Every piece is picked at random
+	Guideline Ruleset = "guideline" // Pieces are dealt from shuffled bags of all seven
+)
+
+// maxLevel is the level at which gravity stops speeding up.
+const maxLevel = 7
+
+// maxPreview is the most next pieces that can be shown.
+const maxPreview = 5
+
+// Config chooses the rules and options of a single player game. It is read
+// from a file, then overridden by environment variables and flags.
+type Config struct {
+	Mode      Mode    `json:"mode"`
+	Seed      int64   `json:"seed"`    // Seeds the pieces, or 0 for a seed from the clock
+	Level     int     `json:"level"`   // Level to start on, from 1 to maxLevel
+	Preview   int     `json:"preview"` // Next pieces shown, from 1 to maxPreview
+	Ruleset   Ruleset `json:"ruleset"`
+	Ranked    bool    `json:"ranked"`   // Ranked runs are played without hints
+	StatsPath string  `json:"stats"`    // Where the stats are saved when the game ends
+	Theme     string  `json:"theme"`    // Directory or zip file of the theme, or empty for the built in one
+	PieceSet  string  `json:"pieceSet"` // Name of a built in piece set or path to one, or empty for the tetrominoes
+}
+
+// DefaultConfig returns the config used when nothing else is given.
+func DefaultConfig() Config {
+	return Config{Mode: Marathon, Level: 1, Preview: 1, Ruleset: Classic}
+}
+
+// Validate returns an error describing the first option of c that is out
+// of range.
+func (c Config) Validate() error {
+	switch c.Mode {
+	case Marathon, Sprint:
+	default:
+		return fmt.Errorf("invalid mode %q: want %q or %q", c.Mode, Marathon, Sprint)
+	}
+	if c.Level < 1 || c.Level > maxLevel {
+		return fmt.Errorf("invalid level %d: want 1 to %d", c.Level, maxLevel)
+	}
+	if c.Preview < 1 || c.Preview > maxPreview {
+		return fmt.Errorf("invalid preview %d: want 1 to %d", c.Preview, maxPreview)
+	}
+	switch c.Ruleset {
+	case Classic, Guideline:
+	default:
+		return fmt.Errorf("invalid ruleset %q: want %q or %q", c.Ruleset, Classic, Guideline)
+	}
+	if _, err := LookupPieceSet(c.PieceSet); err != nil {
+		return err
+	}
+	return nil
+}
+
+// pieceSet returns the piece set of c, which must be valid.
+func (c Config) pieceSet() *PieceSet {
+	s, err := LookupPieceSet(c.PieceSet)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// configPath returns where the config file is kept by default.
+func configPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tetris-go", "config.json"), nil
+}
+
+// LoadConfig reads the config file at path, or the one next to the
+// settings if path is empty, and applies the TETRIS_* environment variables
+// on top. Options missing from both keep their defaults. It is not an error
+// for the default config file to be missing. The config is not validated, so
+// that flags can still be applied to it.
+func LoadConfig(path string) (Config, error) {
+	c := DefaultConfig()
+	explicit := path != ""
+	if !explicit {
+		var err error
+		if path, err = configPath(); err != nil {
+			return c, err
+		}
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) && !explicit:
+	case err != nil:
+		return c, err
+	default:
+		if err := json.Unmarshal(data, &c); err != nil {
+			return c, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	if err := c.applyEnv(os.Getenv); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// applyEnv overrides the options of c that have an environment variable set,
+// as returned by getenv.
+func (c *Config) applyEnv(getenv func(string) string) error {
+	if v := getenv("TETRIS_MODE"); v != "" {
+		c.Mode = Mode(v)
+	}
+	if v := getenv("TETRIS_RULESET"); v != "" {
+		c.Ruleset = Ruleset(v)
+	}
+	if v := getenv("TETRIS_STATS"); v != "" {
+		c.StatsPath = v
+	}
+	if v := getenv("TETRIS_THEME"); v != "" {
+		c.Theme = v
+	}
+	if v := getenv("TETRIS_PIECE_SET"); v != "" {
+		c.PieceSet = v
+	}
+	if v := getenv("TETRIS_SEED"); v != "" {
+		seed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("TETRIS_SEED: %v", err)
+		}
+		c.Seed = seed
+	}
+	if err := envInt(getenv, "TETRIS_LEVEL", &c.Level); err != nil {
+		return err
+	}
+	if err := envInt(getenv, "TETRIS_PREVIEW", &c.Preview); err != nil {
+		return err
+	}
+	if v := getenv("TETRIS_RANKED"); v != "" {
+		ranked, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("TETRIS_RANKED: %v", err)
+		}
+		c.Ranked = ranked
+	}
+	return nil
+}
+
+// envInt sets n to the integer in the environment variable name, if it is
+// set.
+func envInt(getenv func(string) string, name string, n *int) error {
+	v := getenv(name)
+	if v == "" {
+		return nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	*n = i
+	return nil
+}
+
+// levelSpeed returns the time, in seconds, between steps of gravity at the
+// start of a level.
+func levelSpeed(level int) float64 {
+	speed := 0.8 - float64(level-1)*speedUpRate
+	if speed < 0.2 {
+		speed = 0.2
+	}
+	return speed
+}
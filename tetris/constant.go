@@ -1,5 +1,7 @@
 package tetris
 
+import "fmt"
+
 // BoardRows is the height of the game board in terms of blocks
 const BoardRows = 22
 
@@ -13,6 +15,16 @@ type Point struct {
 	col int
 }
 
+// Row returns the row of the point, counting up from the bottom.
+func (p Point) Row() int {
+	return p.row
+}
+
+// Col returns the column of the point, counting from the left.
+func (p Point) Col() int {
+	return p.col
+}
+
 // Block represents the color of the block
 type Block int
 
@@ -37,10 +49,11 @@ const (
 	GraySpecial
 )
 
-// Piece is a constant for a shape of piece. There are 7 classic pieces like L, and O
+// Piece is a kind of piece, the index of its PieceDef in the piece set a
+// board is played with.
 type Piece int
 
-// Various values that the pieces can be
+// The pieces of the Tetrominoes, the seven classic pieces like L and O
 const (
 	IPiece Piece = iota
 	JPiece
@@ -51,9 +64,33 @@ const (
 	ZPiece
 )
 
-// Shape is a type containing four points, which represents the four points
-// making a contiguous 'piece'.
-type Shape [4]Point
+var pieceNames = [...]string{
+	IPiece: "I",
+	JPiece: "J",
+	LPiece: "L",
+	OPiece: "O",
+	SPiece: "S",
+	TPiece: "T",
+	ZPiece: "Z",
+}
+
+// String returns the name of p in the Tetrominoes. Boards name the pieces of
+// other sets after their PieceDef.
+func (p Piece) String() string {
+	if p < 0 || int(p) >= len(pieceNames) {
+		return fmt.Sprintf("Piece(%d)", int(p))
+	}
+	return pieceNames[p]
+}
+
+// Shape is the points making a contiguous 'piece', four of them for the
+// classic pieces.
+type Shape []Point
 
 const levelLength = 60.0 // Time it takes for game to speed up
 const speedUpRate = 0.1  // Every new level, the amount the game speeds up by
+
+// windowWidth and windowHeight are the size of the area one board and its
+// side panels are drawn into
+const windowWidth = 765.0
+const windowHeight = 450.0
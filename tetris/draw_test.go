@@ -0,0 +1,58 @@
+package tetris
+
+import (
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+// discardTarget is a pixel.Target that throws away what is drawn on it, so
+// drawing can be measured without a window.
+type discardTarget struct{}
+
+func (discardTarget) MakeTriangles(t pixel.Triangles) pixel.TargetTriangles {
+	tri := &discardTriangles{}
+	tri.SetLen(t.Len())
+	tri.Update(t)
+	return tri
+}
+
+func (discardTarget) MakePicture(p pixel.Picture) pixel.TargetPicture {
+	return discardPicture{p}
+}
+
+type discardTriangles struct {
+	pixel.TrianglesData
+}
+
+func (*discardTriangles) Draw() {}
+
+type discardPicture struct {
+	pixel.Picture
+}
+
+func (discardPicture) Draw(pixel.TargetTriangles) {}
+
+// BenchmarkDrawFrame draws what a single player game draws every frame, on
+// a board with a few rows filled in.
+func BenchmarkDrawFrame(bench *testing.B) {
+	b := NewSeededBoard(1)
+	b.initResource()
+	b.AddPiece()
+	for i := 0; i < 6; i++ {
+		b.instafall()
+	}
+	var win discardTarget
+	var f finesse
+	help := helpText(defaultKeys.bindings(), English)
+
+	bench.ReportAllocs()
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		b.displayBG(win)
+		b.displayText(win, help)
+		b.displayBoard(win)
+		b.displayFinesse(win, &f, false)
+		b.displayRunFlag(win, "Ranked")
+	}
+}
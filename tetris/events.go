@@ -0,0 +1,147 @@
+package tetris
+
+import "sync"
+
+// Event is something that happened on a Board. It is one of PieceSpawned,
+// PieceMoved, PieceRotated, PieceLocked, LinesCleared, GarbageRaised or
+// GameEnded.
+type Event interface {
+	event()
+}
+
+// PieceSpawned is published when a new piece appears at the top.
+type PieceSpawned struct {
+	Piece Piece
+	Shape Shape
+}
+
+// PieceMoved is published when the active piece moves left, right or down.
+// Action is MoveLeft, MoveRight or Gravity.
+type PieceMoved struct {
+	Shape  Shape
+	Action Action
+}
+
+// PieceRotated is published when the active piece rotates.
+type PieceRotated struct {
+	Shape Shape
+}
+
+// PieceLocked is published when the active piece locks in place, after any
+// rows it completed have been cleared.
+type PieceLocked struct {
+	Piece   Piece
+	Shape   Shape
+	Cleared int  // Rows cleared by the piece
+	TSpin   bool // Whether the piece was a T-spin
+}
+
+// LinesCleared is published when a piece locking clears rows.
+type LinesCleared struct {
+	Rows  int
+	TSpin bool
+}
+
+// GarbageRaised is published when garbage rows rise into the board.
+type GarbageRaised struct {
+	Rows int
+	Hole int // The column left open
+}
+
+// GameEnded is published once, when the board tops out.
+type GameEnded struct {
+	Score int
+}
+
+func (PieceSpawned) event()  {}
+func (PieceMoved) event()    {}
+func (PieceRotated) event()  {}
+func (PieceLocked) event()   {}
+func (LinesCleared) event()  {}
+func (GarbageRaised) event() {}
+func (GameEnded) event()     {}
+
+// EventBus delivers the events of a Board to its subscribers, in the order
+// they happen.
+type EventBus struct {
+	mu       sync.Mutex
+	next     int
+	handlers map[int]func(Event)
+}
+
+// Subscribe calls fn with every event from now on. fn is called
+// synchronously by the board, so it sees the board just after the event
+// happened and must not block. Call the returned function to unsubscribe.
+func (e *EventBus) Subscribe(fn func(Event)) (unsubscribe func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.handlers == nil {
+		e.handlers = make(map[int]func(Event))
+	}
+	id := e.next
+	e.next++
+	e.handlers[id] = fn
+	return func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		delete(e.handlers, id)
+	}
+}
+
+// SubscribeChan sends every event from now on to the returned channel,
+// which holds up to buffer events. The board never waits for a reader, so
+// events that do not fit are dropped. Call the returned function to
+// unsubscribe, which also closes the channel.
+func (e *EventBus) SubscribeChan(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+	// A publish already under way may still call the handler after it has
+	// unsubscribed, so the channel is only sent on while it is open
+	var mu sync.Mutex
+	closed := false
+	unsubscribe := e.Subscribe(func(ev Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return
+		}
+		select {
+		case ch <- ev:
+		default:
+		}
+	})
+	return ch, func() {
+		unsubscribe()
+		mu.Lock()
+		defer mu.Unlock()
+		if !closed {
+			closed = true
+			close(ch)
+		}
+	}
+}
+
+// publish delivers ev to every subscriber.
+func (e *EventBus) publish(ev Event) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	handlers := make([]func(Event), 0, len(e.handlers))
+	for id := 0; id < e.next; id++ {
+		if fn, ok := e.handlers[id]; ok {
+			handlers = append(handlers, fn)
+		}
+	}
+	e.mu.Unlock()
+	for _, fn := range handlers {
+		fn(ev)
+	}
+}
+
+// Events returns the bus the board publishes its events on.
+func (b *Board) Events() *EventBus {
+	if b.events == nil {
+		b.events = &EventBus{}
+	}
+	return b.events
+}
@@ -0,0 +1,50 @@
+package tetris
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSubscribeChan(t *testing.T) {
+	bus := &EventBus{}
+	ch, unsubscribe := bus.SubscribeChan(1)
+	bus.publish(LinesCleared{Rows: 1})
+	bus.publish(LinesCleared{Rows: 2}) // Dropped, the buffer is full
+	if ev := <-ch; ev != (LinesCleared{Rows: 1}) {
+		t.Errorf("got %#v, want the first event", ev)
+	}
+	unsubscribe()
+	unsubscribe()
+	bus.publish(LinesCleared{Rows: 3})
+	if ev, ok := <-ch; ok {
+		t.Errorf("got %#v after unsubscribing", ev)
+	}
+}
+
+// TestSubscribeChanRace unsubscribes while events are being published from
+// another goroutine, which must not send on the closed channel. Run it with
+// -race.
+func TestSubscribeChanRace(t *testing.T) {
+	bus := &EventBus{}
+	for i := 0; i < 200; i++ {
+		ch, unsubscribe := bus.SubscribeChan(4)
+		var wg sync.WaitGroup
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				bus.publish(LinesCleared{Rows: j})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range ch {
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			unsubscribe()
+		}()
+		wg.Wait()
+	}
+}
@@ -0,0 +1,111 @@
+package tetris
+
+import (
+	"fmt"
+
+	"github.com/faiface/pixel"
+	"golang.org/x/image/colornames"
+)
+
+// faultHighlightTime is how long, in seconds, a finesse fault stays
+// highlighted in training.
+const faultHighlightTime = 1.0
+
+// FinesseTraining is what happens when the player makes a finesse fault.
+type FinesseTraining string
+
+// Various finesse training modes
+const (
+	FinesseOff       FinesseTraining = ""          // Faults are only counted
+	FinesseHighlight FinesseTraining = "highlight" // Faults flash the playing field
+	FinesseRestart   FinesseTraining = "restart"   // Faults start the game over
+)
+
+// next returns the mode that follows f when cycling through them.
+func (f FinesseTraining) next() FinesseTraining {
+	switch f {
+	case FinesseOff:
+		return FinesseHighlight
+	case FinesseHighlight:
+		return FinesseRestart
+	}
+	return FinesseOff
+}
+
+// finesse counts the inputs a player uses on each piece and compares them
+// with the fewest that would have placed it in the same spot. Using more
+// than needed is a finesse fault.
+type finesse struct {
+	spawn  Board // The board as the current piece appeared
+	inputs int   // Moves and rotations used on the current piece
+
+	faults     int     // Pieces placed with more inputs than needed
+	lastInputs int     // Inputs used on the piece of the last fault
+	lastNeeded int     // Inputs it needed
+	faultTimer float64 // Time the last fault has left highlighted
+	newFault   bool    // Whether a fault happened since takeFault was called
+}
+
+// start begins tracking the active piece of b.
+func (f *finesse) start(b *Board) {
+	f.spawn = *b
+	f.inputs = 0
+}
+
+// count records an action made by the player. Drops are not counted since
+// gravity makes them anyway.
+func (f *finesse) count(a Action) {
+	if a != Gravity && a != HardDrop {
+		f.inputs++
+	}
+}
+
+// lock checks the inputs used on a piece that locked in shape s.
+func (f *finesse) lock(s Shape) {
+	needed := f.spawn.minInputs(s)
+	if needed < 0 || f.inputs <= needed {
+		return
+	}
+	f.faults++
+	f.lastInputs = f.inputs
+	f.lastNeeded = needed
+	f.faultTimer = faultHighlightTime
+	f.newFault = true
+}
+
+func (f *finesse) tick(dt float64) {
+	if f.faultTimer > 0 {
+		f.faultTimer -= dt
+	}
+}
+
+// takeFault reports whether a fault has happened since it was last called.
+func (f *finesse) takeFault() bool {
+	fault := f.newFault
+	f.newFault = false
+	return fault
+}
+
+// displayFinesse shows the finesse fault counter in the side panel and,
+// when highlight is set, outlines the playing field while a fault is fresh.
+func (b *Board) displayFinesse(win pixel.Target, f *finesse, highlight bool) {
+	finesseTextLocX := screenLayout.finesse.X
+	finesseTextLocY := screenLayout.finesse.Y
+	finesseTxt := b.newText(pixel.V(finesseTextLocX, finesseTextLocY))
+	fmt.Fprintf(finesseTxt, b.lang.tr("Finesse faults %d"), f.faults)
+	finesseTxt.Draw(win, pixel.IM.Scaled(finesseTxt.Orig, 1.2))
+
+	if !highlight || f.faultTimer <= 0 {
+		return
+	}
+	imd := b.newShapes()
+	imd.Color = colornames.Red
+	field := screenLayout.fieldRect()
+	imd.Push(field.Min, field.Max)
+	imd.Rectangle(3)
+	imd.Draw(win)
+
+	faultTxt := b.newText(pixel.V(finesseTextLocX, finesseTextLocY-20))
+	fmt.Fprintf(faultTxt, b.lang.tr("%d inputs, %d needed"), f.lastInputs, f.lastNeeded)
+	faultTxt.Draw(win, pixel.IM.Scaled(faultTxt.Orig, 1.2))
+}
@@ -0,0 +1,161 @@
+//go:build !js
+
+package tetris
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/faiface/pixel/pixelgl"
+)
+
+// padMap binds the actions a player can take to the buttons of a gamepad,
+// numbered as GLFW reports those of an Xbox controller.
+type padMap struct {
+	left      int
+	right     int
+	softDrop  int
+	rotate    int
+	rotateCCW int
+	rotate180 int
+	hardDrop  int
+	pause     int
+}
+
+// defaultPad is the gamepad layout of a single player game.
+var defaultPad = padMap{
+	left:      13,
+	right:     11,
+	softDrop:  12,
+	rotate:    0,
+	rotateCCW: 1,
+	rotate180: 3,
+	hardDrop:  10,
+	pause:     7,
+}
+
+// padButtonNames name the buttons of an Xbox controller in the controls help.
+var padButtonNames = map[int]string{
+	0:  "A",
+	1:  "B",
+	2:  "X",
+	3:  "Y",
+	4:  "LB",
+	5:  "RB",
+	6:  "Back",
+	7:  "Start",
+	10: "Pad Up",
+	11: "Pad Right",
+	12: "Pad Down",
+	13: "Pad Left",
+}
+
+func padButtonName(b int) string {
+	if name, ok := padButtonNames[b]; ok {
+		return name
+	}
+	return fmt.Sprintf("Button %d", b)
+}
+
+// bindings lists what the buttons of m do.
+func (m padMap) bindings() []binding {
+	return []binding{
+		{[]string{padButtonName(m.left), padButtonName(m.right)}, "Move block"},
+		{[]string{padButtonName(m.rotate)}, "Rotate block"},
+		{[]string{padButtonName(m.rotateCCW)}, "Rotate left"},
+		{[]string{padButtonName(m.rotate180)}, "Rotate 180"},
+		{[]string{padButtonName(m.softDrop)}, "Fast fall"},
+		{[]string{padButtonName(m.hardDrop)}, "Instant drop"},
+		{[]string{padButtonName(m.pause)}, "Pause"},
+	}
+}
+
+// forKey returns the gamepad button making the same action as key in keys,
+// or -1 if the action has no gamepad button.
+func (m padMap) forKey(keys keyMap, key button) int {
+	switch key {
+	case keys.left:
+		return m.left
+	case keys.right:
+		return m.right
+	case keys.softDrop:
+		return m.softDrop
+	case keys.rotate:
+		return m.rotate
+	case keys.rotate180:
+		return m.rotate180
+	case keys.hardDrop:
+		return m.hardDrop
+	}
+	for _, b := range keys.rotateCCW {
+		if key == b {
+			return m.rotateCCW
+		}
+	}
+	return -1
+}
+
+// connectedPad returns the first gamepad connected to the computer.
+func connectedPad(win *pixelgl.Window) (pixelgl.Joystick, bool) {
+	for js := pixelgl.Joystick1; js <= pixelgl.JoystickLast; js++ {
+		if win.JoystickPresent(js) {
+			return js, true
+		}
+	}
+	return 0, false
+}
+
+// padJustPressed reports whether button b of the connected gamepad, if any,
+// was pressed since the last frame.
+func padJustPressed(win *pixelgl.Window, b int) bool {
+	js, ok := connectedPad(win)
+	return ok && win.JoystickJustPressed(js, b)
+}
+
+// padInput is a window whose keys can also be pressed with the buttons of a
+// gamepad, so a player can use either.
+type padInput struct {
+	win  *pixelgl.Window
+	keys keyMap
+	pad  padMap
+}
+
+func (in padInput) Pressed(b button) bool {
+	return in.win.Pressed(b) || in.padDo(b, in.win.JoystickPressed)
+}
+
+func (in padInput) JustPressed(b button) bool {
+	return in.win.JustPressed(b) || in.padDo(b, in.win.JoystickJustPressed)
+}
+
+func (in padInput) JustReleased(b button) bool {
+	return in.win.JustReleased(b) || in.padDo(b, in.win.JoystickJustReleased)
+}
+
+// padDo asks state about the gamepad button bound to the same action as
+// key, if a gamepad is connected.
+func (in padInput) padDo(key button, state func(pixelgl.Joystick, int) bool) bool {
+	js, ok := connectedPad(in.win)
+	b := in.pad.forKey(in.keys, key)
+	return ok && b >= 0 && state(js, b)
+}
+
+// ControlsHelp lists the controls of every kind of game as Markdown, the way
+// the README does.
+func ControlsHelp() string {
+	var sb strings.Builder
+	section := func(title string, bs []binding) {
+		fmt.Fprintf(&sb, "%s:\n\n", title)
+		for _, b := range bs {
+			fmt.Fprintf(&sb, "- %s - %s\n", strings.Join(b.buttons, "/"), b.action)
+		}
+		sb.WriteString("\n")
+	}
+	section("Single player", singleBindings())
+	section("Single player with a gamepad", defaultPad.bindings())
+	section("Terminal", terminalBindings())
+	for i, keys := range versusKeys {
+		section(fmt.Sprintf("Versus, %s player", [2]string{"left", "right"}[i]), append(keys.bindings(), pauseBinding))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
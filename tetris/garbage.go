@@ -0,0 +1,115 @@
+package tetris
+
+import (
+	"github.com/faiface/pixel"
+	"golang.org/x/image/colornames"
+)
+
+// attackTable is the number of garbage rows sent to the opponent for
+// clearing 0, 1, 2, 3 or 4 rows with a single piece.
+var attackTable = [5]int{0, 0, 1, 2, 4}
+
+// QueueGarbage adds rows of garbage sent by an opponent. They rise into the
+// board the next time a piece locks without clearing a row.
+func (b *Board) QueueGarbage(rows int) {
+	if rows > 0 {
+		b.pendingGarbage = append(b.pendingGarbage, rows)
+	}
+}
+
+// PendingGarbage returns the number of garbage rows waiting to rise.
+func (b *Board) PendingGarbage() int {
+	total := 0
+	for _, rows := range b.pendingGarbage {
+		total += rows
+	}
+	return total
+}
+
+// TakeAttack returns the garbage rows this board has sent since it was
+// last called.
+func (b *Board) TakeAttack() int {
+	attack := b.outgoingAttack
+	b.outgoingAttack = 0
+	return attack
+}
+
+// settleGarbage is called when a piece locks after clearing the given
+// number of rows. The attack earned first cancels garbage waiting to rise
+// and whatever is left is sent to the opponent. When nothing was cleared the
+// pending garbage rises into the board instead.
+func (b *Board) settleGarbage(cleared int) {
+	if cleared >= len(attackTable) {
+		cleared = len(attackTable) - 1
+	}
+	attack := attackTable[cleared]
+	for attack > 0 && len(b.pendingGarbage) > 0 {
+		if b.pendingGarbage[0] > attack {
+			b.pendingGarbage[0] -= attack
+			attack = 0
+			break
+		}
+		attack -= b.pendingGarbage[0]
+		b.pendingGarbage = b.pendingGarbage[1:]
+	}
+	b.outgoingAttack += attack
+
+	if cleared == 0 {
+		for _, rows := range b.pendingGarbage {
+			b.raiseGarbage(rows, b.garbageRng.Intn(BoardCols))
+		}
+		b.pendingGarbage = nil
+	}
+}
+
+// raiseGarbage pushes every row of the board up by rows and fills the rows
+// opened at the bottom with Gray blocks, leaving column hole empty. Blocks
+// pushed into the invisible rows (ie rows 20 and 21) end the game.
+func (b *Board) raiseGarbage(rows, hole int) {
+	if rows > BoardRows {
+		rows = BoardRows
+	}
+	toppedOut := false
+	for r := BoardRows - 2 - rows; r < BoardRows; r++ {
+		if r < 0 {
+			continue
+		}
+		for c := 0; c < BoardCols; c++ {
+			if b.board[r][c] != Empty {
+				toppedOut = true
+			}
+		}
+	}
+
+	for r := BoardRows - 1; r >= rows; r-- {
+		b.board[r] = b.board[r-rows]
+	}
+	for r := 0; r < rows; r++ {
+		for c := 0; c < BoardCols; c++ {
+			b.board[r][c] = Gray
+		}
+		b.board[r][hole] = Empty
+	}
+	b.events.publish(GarbageRaised{Rows: rows, Hole: hole})
+	if toppedOut {
+		b.endGame()
+	}
+}
+
+// displayGarbageMeter draws a bar beside the playing field showing how many
+// garbage rows are waiting to rise.
+func (b *Board) displayGarbageMeter(win pixel.Target) {
+	pending := b.PendingGarbage()
+	if pending == 0 {
+		return
+	}
+	if pending > BoardRows-2 {
+		pending = BoardRows - 2
+	}
+	field := screenLayout.field
+	imd := b.newShapes()
+	imd.Color = colornames.Red
+	imd.Push(pixel.V(field.X-8, field.Y), pixel.V(field.X-2, field.Y+float64(pending)*screenLayout.block))
+	imd.Rectangle(0)
+	imd.Draw(win)
+}
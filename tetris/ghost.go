@@ -0,0 +1,118 @@
+package tetris
+
+import (
+	"image/color"
+	"log"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/yankooo/tetris-go/tetris/spritesheet"
+)
+
+// GhostStyle is how the ghost, the shadow showing where the active piece
+// would land, is drawn.
+type GhostStyle string
+
+// Various ghost styles
+const (
+	GhostOutline GhostStyle = ""      // A translucent outline around the piece
+	GhostSolid   GhostStyle = "solid" // Translucent filled blocks
+	GhostOff     GhostStyle = "off"   // No ghost
+)
+
+// How opaque the ghost is drawn in each style.
+const (
+	ghostOutlineAlpha = 0.75
+	ghostSolidAlpha   = 0.35
+)
+
+// next returns the style that follows s when cycling through them.
+func (s GhostStyle) next() GhostStyle {
+	switch s {
+	case GhostOutline:
+		return GhostSolid
+	case GhostSolid:
+		return GhostOff
+	}
+	return GhostOutline
+}
+
+// ghostColor returns the colour the settings give the ghost. It returns
+// false if the ghost takes the colour of the piece, which it also does when
+// the colour cannot be read.
+func (s Settings) ghostColor() (color.RGBA, bool) {
+	if s.GhostColor == "" {
+		return color.RGBA{}, false
+	}
+	c, err := spritesheet.ParseColor(s.GhostColor)
+	if err != nil {
+		log.Printf("ghost colour: %v", err)
+		return color.RGBA{}, false
+	}
+	return c, true
+}
+
+// ghostShape returns where the active piece would land if it were dropped
+// now.
+func (b *Board) ghostShape() Shape {
+	return b.landingShape(b.activeShape)
+}
+
+// displayGhost draws the ghost of the active piece in the style and colour
+// of the settings. It is drawn before the blocks, so the active piece covers
+// it where they overlap.
+func (b *Board) displayGhost(win pixel.Target) {
+	if b.ghost == GhostOff {
+		return
+	}
+	c := b.ghostColor
+	if !b.customGhost {
+		c = b.skin.color(b.pieceBlock(b.currentPiece))
+	}
+	s := b.ghostShape()
+	half := screenLayout.block / 2
+	imd := b.newShapes()
+	if b.ghost == GhostSolid {
+		imd.Color = pixel.ToRGBA(c).Mul(pixel.Alpha(ghostSolidAlpha))
+		for _, p := range s {
+			if p.row < BoardRows-2 {
+				v := screenLayout.cell(p.row, p.col)
+				imd.Push(v.Sub(pixel.V(half, half)), v.Add(pixel.V(half, half)))
+				imd.Rectangle(0)
+			}
+		}
+		imd.Draw(win)
+		return
+	}
+
+	imd.Color = pixel.ToRGBA(c).Mul(pixel.Alpha(ghostOutlineAlpha))
+	imd.EndShape = imdraw.SharpEndShape
+	for _, e := range outlineEdges(s) {
+		imd.Push(e[0], e[1])
+		imd.Line(2)
+	}
+	imd.Draw(win)
+}
+
+// outlineEdges returns the ends of the edges around the visible cells of
+// shape s that have no cell of s beyond them, in layout units. They are
+// inset by a pixel so that a line along them stays inside its cells.
+func outlineEdges(s Shape) [][2]pixel.Vec {
+	half := screenLayout.block/2 - 1
+	var edges [][2]pixel.Vec
+	for _, p := range s {
+		if p.row >= BoardRows-2 {
+			continue
+		}
+		v := screenLayout.cell(p.row, p.col)
+		for _, d := range [4]Point{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+			if shapeContains(s, p.row+d.row, p.col+d.col) {
+				continue
+			}
+			mid := v.Add(pixel.V(float64(d.col), float64(d.row)).Scaled(half))
+			along := pixel.V(float64(d.row), float64(d.col)).Scaled(half)
+			edges = append(edges, [2]pixel.Vec{mid.Sub(along), mid.Add(along)})
+		}
+	}
+	return edges
+}
@@ -0,0 +1,129 @@
+package tetris
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// GIFOptions chooses the part of a replay WriteGIF renders and how.
+type GIFOptions struct {
+	From     float64  // Seconds of play the GIF starts at
+	To       float64  // Seconds of play the GIF ends at, or 0 for the end of the replay
+	FPS      int      // Frames a second, or 0 for defaultGIFFPS
+	Scale    float64  // Pixels per layout unit, or 0 for 1
+	Settings Settings // Palette, letters, ghost and language the game is drawn with
+}
+
+// defaultGIFFPS is the frame rate of GIFs when none is asked for. GIF delays
+// are counted in hundredths of a second, so it divides 100.
+const defaultGIFFPS = 10
+
+// gifHold is how long the last frame of a GIF is shown before it loops, in
+// hundredths of a second.
+const gifHold = 200
+
+// WriteGIF plays the replay without a window and writes the part o asks for
+// to w as an animated GIF, one frame for every 1/FPS seconds of play.
+func (r *Replay) WriteGIF(w io.Writer, o GIFOptions) error {
+	if o.FPS == 0 {
+		o.FPS = defaultGIFFPS
+	}
+	if o.Scale == 0 {
+		o.Scale = 1
+	}
+	end := r.Duration()
+	if o.To == 0 || o.To > end {
+		o.To = end
+	}
+	if o.FPS < 1 || o.FPS > 100 || o.Scale <= 0 || o.From < 0 || o.From > o.To {
+		return errors.New("tetris: GIF options out of range")
+	}
+
+	b := r.newBoard()
+	renderer := newFrameRenderer(o.Settings, b.set, o.Scale)
+	quantizer := newGIFQuantizer()
+	anim := &gif.GIF{}
+	delay := 100 / o.FPS
+	next := 0
+	frames := int(math.Floor((o.To-o.From)*float64(o.FPS))) + 1
+	for i := 0; i < frames; i++ {
+		t := o.From + float64(i)/float64(o.FPS)
+		for next < len(r.Actions) && r.Actions[next].Time <= t && !b.GameOver() {
+			b.Apply(r.Actions[next].Action)
+			next++
+		}
+		anim.Image = append(anim.Image, quantizer.paletted(renderer.render(b)))
+		anim.Delay = append(anim.Delay, delay)
+	}
+	anim.Delay[len(anim.Delay)-1] = gifHold
+	return gif.EncodeAll(w, anim)
+}
+
+// SaveGIF writes the GIF made by WriteGIF to path, creating its directory
+// if needed.
+func (r *Replay) SaveGIF(path string, o GIFOptions) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := r.WriteGIF(f, o); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Duration returns the seconds of play the replay covers.
+func (r *Replay) Duration() float64 {
+	if len(r.Actions) == 0 {
+		return 0
+	}
+	return r.Actions[len(r.Actions)-1].Time
+}
+
+// gifQuantizer turns frames into images of the Plan 9 palette. Frames have
+// few colours and most of them repeat from frame to frame, so the palette
+// index of each colour is looked up once.
+type gifQuantizer struct {
+	index map[color.RGBA]uint8
+}
+
+func newGIFQuantizer() *gifQuantizer {
+	return &gifQuantizer{index: make(map[color.RGBA]uint8)}
+}
+
+// paletted returns img in the palette, each pixel as the closest colour.
+func (q *gifQuantizer) paletted(img *image.RGBA) *image.Paletted {
+	out := image.NewPaletted(img.Bounds(), palette.Plan9)
+	for i := 0; i < len(img.Pix); i += 4 {
+		c := color.RGBA{img.Pix[i], img.Pix[i+1], img.Pix[i+2], 0xff}
+		idx, ok := q.index[c]
+		if !ok {
+			idx = uint8(color.Palette(palette.Plan9).Index(c))
+			q.index[c] = idx
+		}
+		out.Pix[i/4] = idx
+	}
+	return out
+}
+
+// capturePath returns where a screenshot or GIF taken at t is saved, with
+// the file extension ext.
+func capturePath(t time.Time, ext string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tetris-go", "captures", t.Format("20060102-150405")+ext), nil
+}
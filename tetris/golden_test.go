@@ -0,0 +1,122 @@
+package tetris
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// boardText draws b as text, top row first: locked blocks by the letter of
+// the tetromino of their colour, # for garbage and @ for the active piece.
+// The two hidden rows are above the line.
+func boardText(b *Board) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "score %d  lines %d  pieces %d  over %v\n", b.score, b.lines, b.pieces, b.gameOver)
+	for r := BoardRows - 1; r >= 0; r-- {
+		if r == BoardRows-3 {
+			sb.WriteString("+" + strings.Repeat("-", BoardCols) + "+\n")
+		}
+		sb.WriteString("|")
+		for c := 0; c < BoardCols; c++ {
+			sb.WriteByte(cellLetter(b, r, c))
+		}
+		sb.WriteString("|\n")
+	}
+	sb.WriteString("+" + strings.Repeat("-", BoardCols) + "+\n")
+	return sb.String()
+}
+
+func cellLetter(b *Board, r, c int) byte {
+	if !b.gameOver && shapeContains(b.activeShape, r, c) {
+		return '@'
+	}
+	switch v := b.board[r][c]; v {
+	case Empty:
+		return '.'
+	case Gray:
+		return '#'
+	default:
+		for _, d := range Tetrominoes.Pieces {
+			if d.Block == v {
+				return d.Name[0]
+			}
+		}
+		return '?'
+	}
+}
+
+// checkGolden compares got with testdata/name.golden, or rewrites the file
+// with -update.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("board differs from %s:\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+// TestGoldenScript plays a fixed list of actions on a seeded board.
+func TestGoldenScript(t *testing.T) {
+	b := NewSeededBoard(1)
+	b.AddPiece()
+	script := []Action{MoveLeft, MoveLeft, MoveLeft, MoveLeft, HardDrop, Rotate, MoveRight, MoveRight, HardDrop,
+		RotateCCW, HardDrop, Rotate180, MoveLeft, Gravity, Gravity, HardDrop, MoveRight, MoveRight, MoveRight, MoveRight, HardDrop}
+	for i := 0; i < 3; i++ {
+		for _, a := range script {
+			b.Apply(a)
+		}
+	}
+	checkGolden(t, "script", boardText(b))
+}
+
+// TestGoldenBot lets the reference bot play games of each ruleset and of
+// other piece sets, snapshotting where each ends up.
+func TestGoldenBot(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		ruleset Ruleset
+		set     string
+		pieces  int
+	}{
+		{"bot-classic", Classic, "tetromino", 60},
+		{"bot-guideline", Guideline, "tetromino", 60},
+		{"bot-pentomino", Guideline, "pentomino", 40},
+	} {
+		set, err := LookupPieceSet(tt.set)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := newRuledBoard(7, tt.ruleset, 1, set)
+		b.AddPiece()
+		playHeadless(b, NewHeuristicBot(), tt.pieces)
+		checkGolden(t, tt.name, boardText(b))
+	}
+}
+
+// TestGoldenGarbage raises garbage under a few locked pieces.
+func TestGoldenGarbage(t *testing.T) {
+	b := NewSeededBoard(3)
+	b.AddPiece()
+	for _, a := range []Action{HardDrop, MoveLeft, MoveLeft, HardDrop} {
+		b.Apply(a)
+	}
+	b.QueueGarbage(2)
+	b.QueueGarbage(1)
+	b.Apply(HardDrop)
+	checkGolden(t, "garbage", boardText(b))
+}
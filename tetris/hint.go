@@ -0,0 +1,52 @@
+package tetris
+
+import (
+	"fmt"
+
+	"github.com/faiface/pixel"
+	"golang.org/x/image/colornames"
+)
+
+// hint is the placement suggested to the player for the current piece.
+type hint struct {
+	piece int // Value of Board.pieces the hint was worked out for, 0 if none has been
+	shape Shape
+	found bool
+}
+
+// update works the hint out again once a new piece has appeared on b. The
+// hint must be cleared when b is reset, since the count of pieces starts
+// again.
+func (h *hint) update(b *Board) {
+	if h.piece == b.pieces {
+		return
+	}
+	h.piece = b.pieces
+	p, found := ElTetrisWeights.best(b.botState())
+	h.shape, h.found = p.Shape, found
+}
+
+// displayHint outlines the cells of shape s on the playing field.
+func (b *Board) displayHint(win pixel.Target, s Shape) {
+	boardBlockSize := screenLayout.block
+	imd := b.newShapes()
+	imd.Color = colornames.Lime
+	for i := range s {
+		if s[i].row >= BoardRows-2 {
+			continue
+		}
+		x := float64(s[i].col)*boardBlockSize + screenLayout.field.X
+		y := float64(s[i].row)*boardBlockSize + screenLayout.field.Y
+		imd.Push(pixel.V(x+1, y+1), pixel.V(x+boardBlockSize-1, y+boardBlockSize-1))
+		imd.Rectangle(2)
+	}
+	imd.Draw(win)
+}
+
+// displayRunFlag writes a note about how the game is being played, such as
+// whether it is ranked, under the score.
+func (b *Board) displayRunFlag(win pixel.Target, flag string) {
+	flagTxt := b.newText(screenLayout.runFlag)
+	fmt.Fprint(flagTxt, flag)
+	flagTxt.Draw(win, pixel.IM.Scaled(flagTxt.Orig, 1.2))
+}
@@ -0,0 +1,226 @@
+package tetris
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/yankooo/tetris-go/tetris/spritesheet"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+)
+
+// Language is the language the game's text is shown in.
+type Language string
+
+// Various languages
+const (
+	English Language = "en"
+	Chinese Language = "zh-CN" // Simplified Chinese
+)
+
+// next returns the language that follows l when cycling through them.
+func (l Language) next() Language {
+	if l == Chinese {
+		return English
+	}
+	return Chinese
+}
+
+// catalogs translate the English text of the game into every other
+// language, keyed by the English text. Formats keep their verbs in order.
+var catalogs = map[Language]map[string]string{
+	Chinese: {
+		"Tetris":     "俄罗斯方块",
+		"Score":      "分数",
+		"Next Piece": "下一个",
+		"Game Pause": "游戏暂停",
+		"Game Over":  "游戏结束",
+
+		"C - Continue": "C - 继续游戏",
+		"N - New game": "N - 新游戏",
+
+		"You Win":      "你赢了",
+		"You Lose":     "你输了",
+		"Draw":         "平局",
+		"Winner":       "胜者",
+		"Disconnected": "连接已断开",
+		"Stream Ended": "直播已结束",
+		"Waiting...":   "等待中...",
+
+		"Finesse faults %d":    "操作失误 %d",
+		"%d inputs, %d needed": "按键 %d 次，只需 %d 次",
+
+		"Replay":       "回放",
+		"Ranked":       "排位",
+		"Hints used":   "用过提示",
+		"Sprint %d/%d": "竞速 %d/%d",
+
+		"Time %s":                "时间 %s",
+		"Pieces %d  PPS %.2f":    "方块 %d  每秒 %.2f",
+		"KPP %.2f  APM %.1f":     "每块按键 %.2f  每分攻击 %.1f",
+		"Lines %d  Max combo %d": "行数 %d  最大连击 %d",
+		"Holes created %d":       "造成空洞 %d",
+		"single":                 "单消",
+		"double":                 "双消",
+		"triple":                 "三消",
+		"tetris":                 "四消",
+		"tspin":                  "T旋",
+		"tspinSingle":            "T旋单消",
+		"tspinDouble":            "T旋双消",
+		"tspinTriple":            "T旋三消",
+
+		"Move block":       "移动方块",
+		"Rotate block":     "旋转方块",
+		"Rotate left":      "向左旋转",
+		"Rotate 180":       "旋转180度",
+		"Fast fall":        "加速下落",
+		"Instant drop":     "直接落下",
+		"Toggle hint":      "开关提示",
+		"Finesse training": "操作训练",
+		"Show stats":       "显示统计",
+		"Colour palette":   "配色",
+		"Piece letters":    "方块字母",
+		"Language":         "语言",
+		"Ghost piece":      "落点阴影",
+		"Screenshot":       "截图",
+		"Save GIF":         "保存动图",
+		"Fullscreen":       "全屏",
+		"Pause":            "暂停",
+
+		// Names of buttons
+		"Left":        "左",
+		"Right":       "右",
+		"Up":          "上",
+		"Down":        "下",
+		"Space":       "空格",
+		"Enter":       "回车",
+		"Click":       "点击",
+		"Right Ctrl":  "右Ctrl",
+		"Right Shift": "右Shift",
+		"Pad Left":    "十字键左",
+		"Pad Right":   "十字键右",
+		"Pad Up":      "十字键上",
+		"Pad Down":    "十字键下",
+	},
+}
+
+// tr returns msg in language l, or msg itself if it has no translation.
+func (l Language) tr(msg string) string {
+	if t, ok := catalogs[l][msg]; ok {
+		return t
+	}
+	return msg
+}
+
+// trLines translates each line of text on its own, keeping the indentation.
+func (l Language) trLines(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		msg := strings.TrimLeftFunc(line, unicode.IsSpace)
+		lines[i] = line[:len(line)-len(msg)] + l.tr(strings.TrimRightFunc(msg, unicode.IsSpace))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// needsFont reports whether l is written with characters the built in font
+// does not have.
+func (l Language) needsFont() bool {
+	return l == Chinese
+}
+
+// catalogRunes returns every character used by the translations.
+func catalogRunes() []rune {
+	seen := make(map[rune]bool)
+	var runes []rune
+	for _, catalog := range catalogs {
+		for _, t := range catalog {
+			for _, r := range t {
+				if !seen[r] {
+					seen[r] = true
+					runes = append(runes, r)
+				}
+			}
+		}
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	return runes
+}
+
+// fontSize is the size, in points, of the fonts loaded for a language. It is
+// close to the height of the built in font.
+const fontSize = 13
+
+// systemFonts are where fonts with Chinese characters are often installed.
+// They are tried in order when the settings do not name a font.
+var systemFonts = []string{
+	"/usr/share/fonts/truetype/wqy/wqy-microhei.ttc",
+	"/usr/share/fonts/truetype/wqy/wqy-zenhei.ttc",
+	"/usr/share/fonts/wenquanyi/wqy-microhei/wqy-microhei.ttc",
+	"/usr/share/fonts/opentype/noto/NotoSansCJK-Regular.ttc",
+	"/usr/share/fonts/noto-cjk/NotoSansCJK-Regular.ttc",
+	"/System/Library/Fonts/PingFang.ttc",
+	"/System/Library/Fonts/STHeiti Light.ttc",
+	"/Library/Fonts/Arial Unicode.ttf",
+	`C:\Windows\Fonts\msyh.ttc`,
+	`C:\Windows\Fonts\simhei.ttf`,
+}
+
+// fontTheme is a theme together with the font file asked for to write a
+// language in.
+type fontTheme struct {
+	theme *spritesheet.Theme
+	path  string
+}
+
+// fontThemes holds every theme given a language font so far.
+var fontThemes = make(map[fontTheme]*spritesheet.Theme)
+
+// languageFont returns t with a font that has the characters of languages
+// the built in font cannot write. The font at path is used, or if path is
+// empty the first of systemFonts that loads. Themes with their own font are
+// trusted to have the characters. If no font loads, t is returned as it is
+// and the missing characters are drawn as replacement characters.
+func languageFont(t *spritesheet.Theme, path string) *spritesheet.Theme {
+	if t.Face != basicfont.Face7x13 {
+		return t
+	}
+	key := fontTheme{t, path}
+	if withFont, ok := fontThemes[key]; ok {
+		return withFont
+	}
+	paths := systemFonts
+	if path != "" {
+		paths = []string{path}
+	}
+	withFont := t
+	for _, p := range paths {
+		face, err := spritesheet.LoadFont(p, fontSize)
+		if err == nil {
+			copied := *t
+			copied.Face = face
+			withFont = &copied
+			break
+		}
+		if path != "" {
+			log.Printf("loading font: %v", err)
+		}
+	}
+	if withFont == t {
+		log.Printf("no font with Chinese characters found, set one in the settings")
+	}
+	fontThemes[key] = withFont
+	return withFont
+}
+
+// coveredRunes returns the runes that face has glyphs for.
+func coveredRunes(face font.Face, runes []rune) []rune {
+	var covered []rune
+	for _, r := range runes {
+		if _, _, ok := face.GlyphBounds(r); ok {
+			covered = append(covered, r)
+		}
+	}
+	return covered
+}
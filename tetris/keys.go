@@ -0,0 +1,34 @@
+//go:build !js
+
+package tetris
+
+import "github.com/faiface/pixel/pixelgl"
+
+// button is a key or mouse button on the window.
+type button = pixelgl.Button
+
+// defaultKeys is the single player layout listed in the controls help.
+var defaultKeys = keyMap{
+	left:      pixelgl.KeyLeft,
+	right:     pixelgl.KeyRight,
+	softDrop:  pixelgl.KeyDown,
+	rotate:    pixelgl.KeyUp,
+	rotateCCW: []button{pixelgl.KeyZ, pixelgl.KeyLeftControl},
+	rotate180: pixelgl.KeyA,
+	hardDrop:  pixelgl.KeySpace,
+}
+
+// buttonName returns the name of b shown in the controls help.
+func buttonName(b button) string {
+	switch b {
+	case pixelgl.MouseButtonLeft:
+		return "Click"
+	case pixelgl.KeyLeftControl:
+		return "Ctrl"
+	case pixelgl.KeyRightControl:
+		return "Right Ctrl"
+	case pixelgl.KeyRightShift:
+		return "Right Shift"
+	}
+	return b.String()
+}
@@ -0,0 +1,33 @@
+//go:build js
+
+package tetris
+
+import "strings"
+
+// button is a key on the keyboard, named by its KeyboardEvent code.
+type button = string
+
+// defaultKeys is the layout used in the browser.
+var defaultKeys = keyMap{
+	left:      "ArrowLeft",
+	right:     "ArrowRight",
+	softDrop:  "ArrowDown",
+	rotate:    "ArrowUp",
+	rotateCCW: []button{"KeyZ", "ControlLeft"},
+	rotate180: "KeyA",
+	hardDrop:  "Space",
+}
+
+// buttonName returns the name of b shown in the controls help, such as
+// Left for ArrowLeft and P for KeyP.
+func buttonName(b button) string {
+	if b == "ControlLeft" {
+		return "Ctrl"
+	}
+	for _, prefix := range []string{"Arrow", "Key", "Digit"} {
+		if name := strings.TrimPrefix(b, prefix); name != b {
+			return name
+		}
+	}
+	return b
+}
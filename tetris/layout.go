@@ -0,0 +1,66 @@
+package tetris
+
+import (
+	"math"
+
+	"github.com/faiface/pixel"
+)
+
+// layout places the parts of a game on screen. Positions are in layout
+// units with the origin at the bottom left, for a screen width by height
+// units. The whole layout is scaled to fit the window it is drawn in.
+type layout struct {
+	width, height float64
+
+	block     float64   // Side of a block on the playing field
+	field     pixel.Vec // Bottom left corner of the playing field
+	scoreBox  pixel.Vec // Centre of the box behind the score
+	nextBox   pixel.Vec // Centre of the box showing the next piece
+	preview   pixel.Vec // Centre of the first of the later pieces in the preview
+	score     pixel.Vec // Score heading, with the score below it
+	runFlag   pixel.Vec // Note on how the game is being played
+	finesse   pixel.Vec // Finesse fault counter
+	nextLabel pixel.Vec // Heading above the next piece
+	help      pixel.Vec // Controls help or live stats
+	message   pixel.Vec // Messages across the playing field, eg "Game Pause"
+}
+
+// screenLayout is the layout of a single board.
+var screenLayout = layout{
+	width:     windowWidth,
+	height:    windowHeight,
+	block:     20,
+	field:     pixel.V(282, 25),
+	scoreBox:  pixel.V(200, 360),
+	nextBox:   pixel.V(150, 120),
+	preview:   pixel.V(250, 270),
+	score:     pixel.V(100, 400),
+	runFlag:   pixel.V(100, 335),
+	finesse:   pixel.V(100, 315),
+	nextLabel: pixel.V(100, 300),
+	help:      pixel.V(460, 350),
+	message:   pixel.V(315, 215),
+}
+
+// fieldRect returns the visible part of the playing field.
+func (l layout) fieldRect() pixel.Rect {
+	return pixel.R(l.field.X, l.field.Y, l.field.X+BoardCols*l.block, l.field.Y+(BoardRows-2)*l.block)
+}
+
+// cell returns the centre of the block at row r and column c of the
+// playing field.
+func (l layout) cell(r, c int) pixel.Vec {
+	return l.field.Add(pixel.V((float64(c)+0.5)*l.block, (float64(r)+0.5)*l.block))
+}
+
+// fit returns the matrix that scales a screen of layouts side by side,
+// width units wide, to fill bounds, centred. The scale is a whole number
+// whenever bounds are big enough, so that blocks stay crisp.
+func (l layout) fit(bounds pixel.Rect, width float64) pixel.Matrix {
+	scale := math.Min(bounds.W()/width, bounds.H()/l.height)
+	if scale >= 1 {
+		scale = math.Floor(scale)
+	}
+	offset := pixel.V((bounds.W()-width*scale)/2, (bounds.H()-l.height*scale)/2)
+	return pixel.IM.Scaled(pixel.ZV, scale).Moved(bounds.Min.Add(offset))
+}
@@ -0,0 +1,143 @@
+package tetris
+
+import "sort"
+
+// moveGenActions are the actions the move generator tries from each position,
+// in the order that breaks ties between equally short paths.
+var moveGenActions = [...]Action{MoveLeft, MoveRight, Rotate, RotateCCW, Rotate180, Gravity}
+
+// Placements returns every position the active piece can be locked in,
+// including those only reached by tucks, spins and soft drops, each with the
+// shortest list of actions that takes the piece there from where it is now.
+// Gravity stands for dropping the piece by a single row, and every list ends
+// with the HardDrop that locks the piece.
+func (b *Board) Placements() []Placement {
+	type node struct {
+		pose   pose
+		parent int
+		action Action
+	}
+	nodes := []node{{pose: b.activePose, parent: -1}}
+	seen := make(map[pose]bool, 256)
+	seen[b.activePose] = true
+	landed := make(map[string]bool, 64)
+	var placements []Placement
+
+	// Breadth first, so each position is first reached by a shortest path
+	for i := 0; i < len(nodes); i++ {
+		ps := nodes[i].pose
+
+		landing := b.landingShape(b.active().shape(ps))
+		if key := shapeKey(landing); !landed[key] {
+			landed[key] = true
+			actions := []Action{HardDrop}
+			for n := i; nodes[n].parent >= 0; n = nodes[n].parent {
+				actions = append(actions, nodes[n].action)
+			}
+			for l, r := 0, len(actions)-1; l < r; l, r = l+1, r-1 {
+				actions[l], actions[r] = actions[r], actions[l]
+			}
+			placements = append(placements, Placement{Shape: landing, Actions: actions})
+		}
+
+		for _, a := range moveGenActions {
+			next, ok := b.stepPose(ps, a)
+			if ok && !seen[next] {
+				seen[next] = true
+				nodes = append(nodes, node{pose: next, parent: i, action: a})
+			}
+		}
+	}
+	return placements
+}
+
+// minInputs returns the fewest moves and rotations that take the active piece
+// to lock in target. Drops are not counted since gravity makes them anyway.
+// Returns -1 if target cannot be reached.
+func (b *Board) minInputs(target Shape) int {
+	def := b.active()
+	targetKey := shapeKey(target)
+
+	// Breadth first by number of inputs, where drops lead to positions
+	// with the same count as the one they came from
+	dist := map[pose]int{b.activePose: 0}
+	level := []pose{b.activePose}
+	for inputs := 0; len(level) > 0; inputs++ {
+		var next []pose
+		for i := 0; i < len(level); i++ {
+			ps := level[i]
+			if dist[ps] < inputs {
+				continue
+			}
+			if shapeKey(b.landingShape(def.shape(ps))) == targetKey {
+				return inputs
+			}
+			for _, a := range moveGenActions {
+				n, ok := b.stepPose(ps, a)
+				if !ok {
+					continue
+				}
+				cost := inputs
+				if a != Gravity {
+					cost++
+				}
+				if d, seen := dist[n]; seen && d <= cost {
+					continue
+				}
+				dist[n] = cost
+				if a == Gravity {
+					level = append(level, n)
+				} else {
+					next = append(next, n)
+				}
+			}
+		}
+		level = next
+	}
+	return -1
+}
+
+// stepPose returns where an action moves the active piece from pose ps
+// without locking it. Returns false if the action cannot be made.
+func (b *Board) stepPose(ps pose, a Action) (pose, bool) {
+	next := ps
+	switch a {
+	case MoveLeft:
+		next.col--
+	case MoveRight:
+		next.col++
+	case Gravity:
+		next.row--
+	case Rotate, RotateCCW, Rotate180:
+		return b.kickRotation(ps, a)
+	default:
+		return ps, false
+	}
+	if b.checkCollision(b.active().shape(next)) {
+		return ps, false
+	}
+	return next, true
+}
+
+// landingShape returns where shape s comes to rest if dropped straight down.
+func (b *Board) landingShape(s Shape) Shape {
+	for !b.checkCollision(moveShapeDown(s)) {
+		s = moveShapeDown(s)
+	}
+	return s
+}
+
+// shapeKey identifies the cells covered by a shape, whatever order its
+// points are in, so that shapes covering the same cells get the same key.
+func shapeKey(s Shape) string {
+	sorted := append(Shape(nil), s...)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		return a.row < b.row || (a.row == b.row && a.col < b.col)
+	})
+	key := make([]byte, 0, 2*len(sorted))
+	for _, p := range sorted {
+		key = append(key, byte(p.row), byte(p.col))
+	}
+	return string(key)
+}
@@ -0,0 +1,287 @@
+//go:build !js
+
+package tetris
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/yankooo/tetris-go/tetris/netplay"
+	"golang.org/x/image/colornames"
+)
+
+// netSession is a client's seat in a network match. It holds a board for
+// every seat and keeps the copies of the other players' boards in step by
+// replaying the actions they send.
+type netSession struct {
+	conn     *netplay.Conn
+	seat     int
+	boards   []*Board
+	seq      int
+	incoming chan netplay.Message
+	lost     bool  // Whether the connection dropped before the match was over
+	err      error // Why the connection dropped
+
+	toppedOut bool
+	isOver    bool
+	winner    int
+}
+
+// joinMatch connects to the server at addr and waits for the match to start.
+func joinMatch(addr, name string) (*netSession, error) {
+	conn, err := netplay.Dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	s, err := startSession(conn, name)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func startSession(conn *netplay.Conn, name string) (*netSession, error) {
+	err := conn.Send(netplay.Message{Kind: netplay.KindHello, Version: netplay.Version, Name: name})
+	if err != nil {
+		return nil, err
+	}
+	welcome, err := expect(conn, netplay.KindWelcome)
+	if err != nil {
+		return nil, err
+	}
+	start, err := expect(conn, netplay.KindStart)
+	if err != nil {
+		return nil, err
+	}
+	if welcome.Player < 0 || welcome.Player >= start.Players {
+		return nil, fmt.Errorf("server seated us at %d of %d players", welcome.Player, start.Players)
+	}
+
+	s := &netSession{
+		conn:     conn,
+		seat:     welcome.Player,
+		boards:   make([]*Board, start.Players),
+		incoming: make(chan netplay.Message, 64),
+	}
+	for i := range s.boards {
+		s.boards[i] = NewSeededBoard(start.Seed)
+		s.boards[i].AddPiece()
+	}
+	go s.receive()
+	return s, nil
+}
+
+// expect receives the next message, failing if it is not of the given kind.
+func expect(conn *netplay.Conn, kind netplay.Kind) (netplay.Message, error) {
+	m, err := conn.Receive()
+	if err != nil {
+		return m, err
+	}
+	if m.Kind == netplay.KindError {
+		return m, errors.New(m.Error)
+	}
+	if m.Kind != kind {
+		return m, fmt.Errorf("expected %s from server, got %s", kind, m.Kind)
+	}
+	return m, nil
+}
+
+func (s *netSession) receive() {
+	for {
+		m, err := s.conn.Receive()
+		if err != nil {
+			s.err = err
+			close(s.incoming)
+			return
+		}
+		s.incoming <- m
+	}
+}
+
+// local returns the board this client is playing.
+func (s *netSession) local() *Board {
+	return s.boards[s.seat]
+}
+
+// sendAction tells the other players about an action applied to the local
+// board, along with any garbage and top out it caused.
+func (s *netSession) sendAction(a Action) {
+	s.seq++
+	s.conn.Send(netplay.Message{Kind: netplay.KindInput, Seq: s.seq, Action: int(a)})
+	if rows := s.local().TakeAttack(); rows > 0 {
+		s.conn.Send(netplay.Message{Kind: netplay.KindAttack, Rows: rows})
+	}
+	if s.local().GameOver() && !s.toppedOut {
+		s.toppedOut = true
+		s.conn.Send(netplay.Message{Kind: netplay.KindTopOut})
+	}
+}
+
+// poll handles every message received since it was last called. Returns
+// false once the connection is gone.
+func (s *netSession) poll() bool {
+	for {
+		select {
+		case m, ok := <-s.incoming:
+			if !ok {
+				if !s.isOver {
+					s.lost = true
+					s.isOver = true
+					s.winner = -1
+				}
+				return false
+			}
+			s.handle(m)
+		default:
+			return true
+		}
+	}
+}
+
+func (s *netSession) handle(m netplay.Message) {
+	remote := m.Player >= 0 && m.Player < len(s.boards) && m.Player != s.seat
+	switch m.Kind {
+	case netplay.KindInput:
+		if a := Action(m.Action); remote && a.valid() && !s.boards[m.Player].GameOver() {
+			s.boards[m.Player].Apply(a)
+			s.boards[m.Player].TakeAttack()
+		}
+	case netplay.KindQueued:
+		if remote {
+			s.boards[m.Player].QueueGarbage(m.Rows)
+		}
+	case netplay.KindGarbage:
+		if !s.toppedOut {
+			s.local().QueueGarbage(m.Rows)
+			s.seq++
+			s.conn.Send(netplay.Message{Kind: netplay.KindQueued, Seq: s.seq, Rows: m.Rows})
+		}
+	case netplay.KindTopOut:
+		if remote {
+			s.boards[m.Player].endGame()
+		}
+	case netplay.KindOver:
+		s.isOver = true
+		s.winner = m.Winner
+	}
+}
+
+// netGame plays the local board of a network match in a window, with the
+// boards of the other players drawn alongside it.
+type netGame struct {
+	win     *pixelgl.Window
+	addr    string
+	name    string
+	session *netSession
+	player  *player
+	screen  pixel.Matrix // Fits every seat to the window, updated every frame
+}
+
+func NewNetGame(addr, name string) *netGame {
+	g := &netGame{addr: addr, name: name}
+	return g
+}
+
+func (g *netGame) Initialize() {
+	var err error
+	g.session, err = joinMatch(g.addr, g.name)
+	if err != nil {
+		panic(err)
+	}
+	settings := savedSettings()
+	g.win = newWindow(float64(len(g.session.boards))*windowWidth, settings.Language)
+	for _, b := range g.session.boards {
+		b.useSettings(settings)
+	}
+	g.player = newPlayer(g.session.local(), defaultKeys)
+	g.player.onAction = g.session.sendAction
+}
+
+func (g *netGame) Run() {
+	defer g.session.conn.Close()
+	last := time.Now()
+	for !g.win.Closed() {
+		g.session.poll()
+		g.screen = fitWindow(g.win, float64(len(g.session.boards))*windowWidth)
+
+		dt := time.Since(last).Seconds()
+		last = time.Now()
+		if !g.session.isOver && !g.session.toppedOut {
+			g.player.update(g.win, dt)
+		}
+
+		g.draw()
+		g.win.Update()
+	}
+}
+
+// draw shows the local board on the left and the other seats in order after
+// it.
+func (g *netGame) draw() {
+	g.win.Clear(colornames.Black)
+	order := []int{g.session.seat}
+	for i := range g.session.boards {
+		if i != g.session.seat {
+			order = append(order, i)
+		}
+	}
+	for pos, seat := range order {
+		g.win.SetMatrix(playerMatrix(pos).Chained(g.screen))
+		g.session.boards[seat].displayBG(g.win)
+	}
+	for pos, seat := range order {
+		b := g.session.boards[seat]
+		g.win.SetMatrix(playerMatrix(pos).Chained(g.screen))
+		help := ""
+		if seat == g.session.seat {
+			help = helpText(g.player.keys.bindings(), b.lang)
+		}
+		b.displayText(g.win, help)
+		b.displayBoard(g.win)
+		switch {
+		case g.session.isOver && g.session.winner == seat:
+			b.displayMessage(g.win, "Winner")
+		case g.session.lost:
+			b.displayMessage(g.win, "Disconnected")
+		case b.GameOver():
+			b.displayMessage(g.win, "Game Over")
+		}
+	}
+	g.win.SetMatrix(pixel.IM)
+}
+
+// RunScript joins the match at addr as a headless player that applies the
+// actions in script one after another, one every interval, going back to
+// the start of the script when it runs out. It is meant for trying network
+// play on a single machine. Returns the winner of the match.
+func RunScript(addr, name string, script []Action, interval time.Duration) (int, error) {
+	if len(script) == 0 {
+		return -1, errors.New("empty script")
+	}
+	s, err := joinMatch(addr, name)
+	if err != nil {
+		return -1, err
+	}
+	defer s.conn.Close()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for i := 0; ; i++ {
+		<-ticker.C
+		if !s.poll() && s.lost {
+			return -1, s.err
+		}
+		if s.isOver {
+			return s.winner, nil
+		}
+		if !s.toppedOut {
+			a := script[i%len(script)]
+			s.local().Apply(a)
+			s.sendAction(a)
+		}
+	}
+}
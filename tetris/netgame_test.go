@@ -0,0 +1,93 @@
+//go:build !js
+
+package tetris
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/yankooo/tetris-go/tetris/netplay"
+)
+
+// relayFirstReply listens on a free local port and relays one connection to
+// addr. The returned channel is closed once addr has first answered, which
+// for a match server means the client has been seated.
+func relayFirstReply(t *testing.T, addr string) (string, <-chan struct{}) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	answered := make(chan struct{})
+	go func() {
+		client, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer client.Close()
+		server, err := net.Dial("tcp", addr)
+		if err != nil {
+			return
+		}
+		defer server.Close()
+		go io.Copy(server, client)
+		buf := make([]byte, 1)
+		if _, err := server.Read(buf); err != nil {
+			return
+		}
+		client.Write(buf)
+		close(answered)
+		io.Copy(client, server)
+	}()
+	return ln.Addr().String(), answered
+}
+
+func TestRunScriptMatch(t *testing.T) {
+	s, err := netplay.Listen("127.0.0.1:0", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Seed = 7
+	defer s.Close()
+	go s.ServeMatch()
+
+	type result struct {
+		winner int
+		err    error
+	}
+	run := func(addr string, script []Action) <-chan result {
+		done := make(chan result, 1)
+		go func() {
+			winner, err := RunScript(addr, "test", script, time.Millisecond)
+			done <- result{winner, err}
+		}()
+		return done
+	}
+
+	// Seat 0 only drops pieces, so it tops out, while seat 1 only moves and
+	// so never locks one
+	relay, seated := relayFirstReply(t, s.Addr().String())
+	dropper := run(relay, []Action{HardDrop})
+	select {
+	case <-seated:
+	case <-time.After(5 * time.Second):
+		t.Fatal("first client was not seated")
+	}
+	mover := run(s.Addr().String(), []Action{MoveLeft, MoveRight})
+
+	for name, done := range map[string]<-chan result{"dropper": dropper, "mover": mover} {
+		select {
+		case r := <-done:
+			if r.err != nil {
+				t.Errorf("%s: %v", name, r.err)
+			} else if r.winner != 1 {
+				t.Errorf("%s was told seat %d won, want 1", name, r.winner)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("%s: the match did not end", name)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package netplay
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"sync"
+)
+
+// Conn sends and receives Messages over a network connection. Send may be
+// called from several goroutines at once.
+type Conn struct {
+	c   net.Conn
+	dec *json.Decoder
+
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewConn(c net.Conn) *Conn {
+	return &Conn{
+		c:   c,
+		dec: json.NewDecoder(bufio.NewReader(c)),
+		enc: json.NewEncoder(c),
+	}
+}
+
+// Dial connects to the match server listening on addr.
+func Dial(addr string) (*Conn, error) {
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(c), nil
+}
+
+// Send writes m to the connection.
+func (c *Conn) Send(m Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.enc.Encode(m)
+}
+
+// Receive blocks until the next message arrives.
+func (c *Conn) Receive() (Message, error) {
+	var m Message
+	err := c.dec.Decode(&m)
+	return m, err
+}
+
+func (c *Conn) Close() error {
+	return c.c.Close()
+}
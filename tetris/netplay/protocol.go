@@ -0,0 +1,73 @@
+// Package netplay is the protocol and match server used to play versus over
+// a network.
+//
+// Every message is a JSON encoded Message on a line of its own, sent over a
+// TCP connection. A match goes as follows:
+//
+//  1. Each client connects and sends KindHello with the protocol Version.
+//  2. The server replies KindWelcome carrying the client's Player index, or
+//     KindError if the version is not supported.
+//  3. Once every seat is taken the server sends KindStart with the Seed all
+//     boards are created with.
+//  4. Clients play their own board locally and send every action applied to
+//     it as KindInput, in order. Since boards sharing a seed are
+//     deterministic, the server forwards these to the other clients, who
+//     replay them on a copy of the sender's board.
+//  5. Rows a client sends to an opponent are reported with KindAttack. The
+//     server picks the target and passes them on as KindGarbage. The target
+//     queues the garbage on its board and sends KindQueued so the copies of
+//     its board queue it at the same point.
+//  6. A client whose board tops out sends KindTopOut. When at most one board
+//     is left the server sends KindOver and closes the match.
+package netplay
+
+// Version is the protocol version spoken by this package. The server refuses
+// clients speaking another version.
+const Version = 1
+
+// Kind identifies what a Message means and which of its fields are set.
+type Kind string
+
+// Various kinds of message. Fields other than Kind that are used by each are
+// listed alongside it.
+const (
+	// KindHello is the first message a client sends. Version, Name.
+	KindHello Kind = "hello"
+	// KindWelcome accepts a client into the match. Player.
+	KindWelcome Kind = "welcome"
+	// KindStart starts the match once it is full. Seed, Players.
+	KindStart Kind = "start"
+	// KindInput is an action applied to a board, sent by its owner and
+	// forwarded to everyone else. Player, Seq, Action.
+	KindInput Kind = "input"
+	// KindAttack reports garbage rows sent by a board. Player, Rows.
+	KindAttack Kind = "attack"
+	// KindGarbage delivers garbage rows to their target. From, Rows.
+	KindGarbage Kind = "garbage"
+	// KindQueued is sent by a board that queued garbage it received, and
+	// forwarded to everyone else. Player, Seq, Rows.
+	KindQueued Kind = "queued"
+	// KindTopOut is sent when a board tops out, and forwarded to everyone
+	// else. Player.
+	KindTopOut Kind = "topout"
+	// KindOver ends the match. Winner is -1 if no board is left.
+	KindOver Kind = "over"
+	// KindError is sent by the server before it drops a client. Error.
+	KindError Kind = "error"
+)
+
+// Message is the single type sent in both directions.
+type Message struct {
+	Kind    Kind   `json:"kind"`
+	Version int    `json:"version,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Player  int    `json:"player"`
+	Players int    `json:"players,omitempty"`
+	Seed    int64  `json:"seed,omitempty"`
+	Seq     int    `json:"seq,omitempty"`    // Position of the message in its sender's stream
+	Action  int    `json:"action,omitempty"` // A tetris.Action
+	From    int    `json:"from,omitempty"`
+	Rows    int    `json:"rows,omitempty"`
+	Winner  int    `json:"winner,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
@@ -0,0 +1,262 @@
+package netplay
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// helloTimeout is how long a new connection has to send KindHello.
+	helloTimeout = 5 * time.Second
+	// writeTimeout is how long a player has to take in a message before
+	// they are dropped from the match.
+	writeTimeout = 5 * time.Second
+)
+
+// Server runs versus matches between clients connecting over TCP.
+type Server struct {
+	Seed int64 // Seed sent to the boards of every match, a random one if 0
+
+	players      int
+	ln           net.Listener
+	writeTimeout time.Duration
+
+	acceptOnce sync.Once
+	greeted    chan *Conn    // Clients that completed the handshake, waiting for a seat
+	closed     chan struct{} // Closed once the listener fails, with the error in acceptErr
+	acceptErr  error
+}
+
+// Listen starts a server on addr for matches between the given number of
+// players.
+func Listen(addr string, players int) (*Server, error) {
+	if players < 2 {
+		return nil, fmt.Errorf("netplay: a match needs at least 2 players, got %d", players)
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		players:      players,
+		ln:           ln,
+		writeTimeout: writeTimeout,
+		greeted:      make(chan *Conn),
+		closed:       make(chan struct{}),
+	}, nil
+}
+
+// Addr returns the address the server is listening on.
+func (s *Server) Addr() net.Addr {
+	return s.ln.Addr()
+}
+
+// Close stops the server from accepting new players.
+func (s *Server) Close() error {
+	return s.ln.Close()
+}
+
+// Serve runs matches one after another until the server is closed.
+func (s *Server) Serve() error {
+	for {
+		if err := s.ServeMatch(); err != nil {
+			return err
+		}
+	}
+}
+
+// ServeMatch waits for a match to fill up and then relays messages between
+// its players until it is over. Players are seated in the order they
+// complete the handshake.
+func (s *Server) ServeMatch() error {
+	s.acceptOnce.Do(func() { go s.accept() })
+	seats := make([]*Conn, 0, s.players)
+	for len(seats) < s.players {
+		select {
+		case conn := <-s.greeted:
+			if err := conn.Send(Message{Kind: KindWelcome, Player: len(seats)}); err != nil {
+				conn.Close()
+				continue
+			}
+			seats = append(seats, conn)
+		case <-s.closed:
+			for _, seat := range seats {
+				seat.Close()
+			}
+			return s.acceptErr
+		}
+	}
+
+	seed := s.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	m := &match{seats: seats, alive: make([]bool, len(seats)), writeTimeout: s.writeTimeout}
+	m.run(seed)
+	return nil
+}
+
+// accept takes new connections until the listener is closed, greeting each
+// on its own so that a client slow to say hello holds up nobody else.
+func (s *Server) accept() {
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			s.acceptErr = err
+			close(s.closed)
+			return
+		}
+		go func() {
+			conn := NewConn(c)
+			if err := greet(c, conn); err != nil {
+				log.Printf("netplay: rejected %s: %v", c.RemoteAddr(), err)
+				conn.Close()
+				return
+			}
+			select {
+			case s.greeted <- conn:
+			case <-s.closed:
+				conn.Close()
+			}
+		}()
+	}
+}
+
+// greet checks the hello of a new client. The client is welcomed once it
+// has a seat.
+func greet(c net.Conn, conn *Conn) error {
+	c.SetReadDeadline(time.Now().Add(helloTimeout))
+	hello, err := conn.Receive()
+	c.SetReadDeadline(time.Time{})
+	if err != nil {
+		return err
+	}
+	if hello.Kind != KindHello {
+		err = fmt.Errorf("expected %s, got %s", KindHello, hello.Kind)
+	} else if hello.Version != Version {
+		err = fmt.Errorf("unsupported protocol version %d, server speaks %d", hello.Version, Version)
+	}
+	if err != nil {
+		conn.Send(Message{Kind: KindError, Error: err.Error()})
+	}
+	return err
+}
+
+// match relays messages between the seated players of a running match.
+type match struct {
+	seats        []*Conn
+	alive        []bool
+	left         int
+	writeTimeout time.Duration
+}
+
+// envelope is a message received from the player in a seat, or the error that
+// ended its connection.
+type envelope struct {
+	player int
+	msg    Message
+	err    error
+}
+
+func (m *match) run(seed int64) {
+	incoming := make(chan envelope)
+	done := make(chan struct{})
+	defer close(done)
+	for i, c := range m.seats {
+		go func(i int, c *Conn) {
+			for {
+				msg, err := c.Receive()
+				select {
+				case incoming <- envelope{player: i, msg: msg, err: err}:
+				case <-done:
+					return
+				}
+				if err != nil {
+					return
+				}
+			}
+		}(i, c)
+	}
+
+	for i := range m.alive {
+		m.alive[i] = true
+	}
+	m.left = len(m.seats)
+	m.broadcast(-1, Message{Kind: KindStart, Seed: seed, Players: len(m.seats)})
+
+	for m.left > 1 {
+		e := <-incoming
+		if e.err != nil {
+			m.topOut(e.player)
+			continue
+		}
+		switch e.msg.Kind {
+		case KindInput, KindQueued:
+			e.msg.Player = e.player
+			m.broadcast(e.player, e.msg)
+		case KindAttack:
+			if target := m.target(e.player); target >= 0 && e.msg.Rows > 0 {
+				m.send(target, Message{Kind: KindGarbage, From: e.player, Rows: e.msg.Rows})
+			}
+		case KindTopOut:
+			m.topOut(e.player)
+		}
+	}
+
+	winner := -1
+	for i, alive := range m.alive {
+		if alive {
+			winner = i
+		}
+	}
+	m.broadcast(-1, Message{Kind: KindOver, Winner: winner})
+	for _, c := range m.seats {
+		c.Close()
+	}
+}
+
+// broadcast sends msg to every player except the one in seat from.
+func (m *match) broadcast(from int, msg Message) {
+	for i := range m.seats {
+		if i != from {
+			m.send(i, msg)
+		}
+	}
+}
+
+// send sends msg to the player in seat i. A player that cannot be sent to
+// in time is dropped from the match, as when their connection breaks, so
+// that they do not hold up everyone else.
+func (m *match) send(i int, msg Message) {
+	c := m.seats[i]
+	c.c.SetWriteDeadline(time.Now().Add(m.writeTimeout))
+	if err := c.Send(msg); err != nil {
+		c.Close()
+		m.topOut(i)
+	}
+}
+
+// topOut removes a player from the match and tells everyone else.
+func (m *match) topOut(player int) {
+	if !m.alive[player] {
+		return
+	}
+	m.alive[player] = false
+	m.left--
+	m.broadcast(player, Message{Kind: KindTopOut, Player: player})
+}
+
+// target picks who receives garbage sent by player: the next player still
+// in the match, going round the seats. Returns -1 if there is nobody.
+func (m *match) target(player int) int {
+	for i := 1; i < len(m.seats); i++ {
+		t := (player + i) % len(m.seats)
+		if m.alive[t] {
+			return t
+		}
+	}
+	return -1
+}
@@ -0,0 +1,169 @@
+package netplay
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// startServer runs matches between two players on a free local port until
+// the test ends. configure, if given, is applied to the server first.
+func startServer(t *testing.T, configure ...func(*Server)) *Server {
+	t.Helper()
+	s, err := Listen("127.0.0.1:0", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Seed = 42
+	for _, f := range configure {
+		f(s)
+	}
+	go s.Serve()
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// dial connects to s, failing the test if it takes long to answer.
+func dial(t *testing.T, s *Server) *Conn {
+	t.Helper()
+	c, err := Dial(s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.c.SetDeadline(time.Now().Add(5 * time.Second))
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// join says hello to the server and returns the seat it was given.
+func join(t *testing.T, c *Conn) int {
+	t.Helper()
+	if err := c.Send(Message{Kind: KindHello, Version: Version}); err != nil {
+		t.Fatal(err)
+	}
+	m := receive(t, c, KindWelcome)
+	return m.Player
+}
+
+// receive returns the next message, failing the test if it is not of the
+// given kind.
+func receive(t *testing.T, c *Conn, kind Kind) Message {
+	t.Helper()
+	m, err := c.Receive()
+	if err != nil {
+		t.Fatalf("waiting for %s: %v", kind, err)
+	}
+	if m.Kind != kind {
+		t.Fatalf("got %+v, want %s", m, kind)
+	}
+	return m
+}
+
+func TestVersionMismatch(t *testing.T) {
+	s := startServer(t)
+	old := dial(t, s)
+	if err := old.Send(Message{Kind: KindHello, Version: Version + 1}); err != nil {
+		t.Fatal(err)
+	}
+	if m := receive(t, old, KindError); m.Error == "" {
+		t.Error("the error does not say what is wrong")
+	}
+	if _, err := old.Receive(); err == nil {
+		t.Error("the connection was not closed after the error")
+	}
+
+	// The seat refused to the old client is still free
+	if seat := join(t, dial(t, s)); seat != 0 {
+		t.Errorf("first client after the refused one got seat %d, want 0", seat)
+	}
+}
+
+func TestMatchRelaysInOrder(t *testing.T) {
+	s := startServer(t)
+	a, b := dial(t, s), dial(t, s)
+	if seat := join(t, a); seat != 0 {
+		t.Fatalf("first client got seat %d", seat)
+	}
+	if seat := join(t, b); seat != 1 {
+		t.Fatalf("second client got seat %d", seat)
+	}
+	for _, c := range []*Conn{a, b} {
+		if m := receive(t, c, KindStart); m.Seed != 42 || m.Players != 2 {
+			t.Fatalf("start %+v, want seed 42 and 2 players", m)
+		}
+	}
+
+	// An attack made by an action reaches its target after the action
+	a.Send(Message{Kind: KindInput, Seq: 1, Action: 5})
+	a.Send(Message{Kind: KindAttack, Rows: 2})
+	if m := receive(t, b, KindInput); m.Player != 0 || m.Seq != 1 || m.Action != 5 {
+		t.Errorf("forwarded input %+v", m)
+	}
+	if m := receive(t, b, KindGarbage); m.From != 0 || m.Rows != 2 {
+		t.Errorf("garbage %+v, want 2 rows from 0", m)
+	}
+
+	// The target's copies queue the garbage between the same actions as the
+	// target did
+	b.Send(Message{Kind: KindInput, Seq: 1, Action: 1})
+	b.Send(Message{Kind: KindQueued, Seq: 2, Rows: 2})
+	b.Send(Message{Kind: KindInput, Seq: 3, Action: 2})
+	for seq, kind := range []Kind{KindInput, KindQueued, KindInput} {
+		if m := receive(t, a, kind); m.Player != 1 || m.Seq != seq+1 {
+			t.Errorf("message %d forwarded as %+v", seq+1, m)
+		}
+	}
+
+	b.Send(Message{Kind: KindTopOut})
+	if m := receive(t, a, KindTopOut); m.Player != 1 {
+		t.Errorf("top out of %d, want 1", m.Player)
+	}
+	for _, c := range []*Conn{a, b} {
+		if m := receive(t, c, KindOver); m.Winner != 0 {
+			t.Errorf("winner %d, want 0", m.Winner)
+		}
+	}
+}
+
+func TestSilentClientHoldsNoSeat(t *testing.T) {
+	s := startServer(t)
+	dial(t, s) // Connects but never says hello
+	a, b := dial(t, s), dial(t, s)
+	for _, c := range []*Conn{a, b} {
+		// Well before the silent client's hello times out
+		c.c.SetDeadline(time.Now().Add(time.Second))
+	}
+	if seat := join(t, a); seat != 0 {
+		t.Errorf("first client to say hello got seat %d, want 0", seat)
+	}
+	if seat := join(t, b); seat != 1 {
+		t.Errorf("second client to say hello got seat %d, want 1", seat)
+	}
+	for _, c := range []*Conn{a, b} {
+		receive(t, c, KindStart)
+	}
+}
+
+func TestStalledPlayerDropped(t *testing.T) {
+	s := startServer(t, func(s *Server) { s.writeTimeout = 100 * time.Millisecond })
+	a, b := dial(t, s), dial(t, s)
+	join(t, a)
+	join(t, b)
+	for _, c := range []*Conn{a, b} {
+		receive(t, c, KindStart)
+	}
+
+	// b stops reading, so the moves relayed to it pile up until the server
+	// gives up on it
+	go func() {
+		padding := strings.Repeat("x", 64<<10)
+		for a.Send(Message{Kind: KindInput, Error: padding}) == nil {
+		}
+	}()
+	if m := receive(t, a, KindTopOut); m.Player != 1 {
+		t.Errorf("top out of %d, want 1", m.Player)
+	}
+	if m := receive(t, a, KindOver); m.Winner != 0 {
+		t.Errorf("winner %d, want 0", m.Winner)
+	}
+}
@@ -0,0 +1,267 @@
+package netplay
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+)
+
+// viewerBacklog is how many frames a spectator may fall behind before it is
+// dropped, so that a slow viewer never holds up the game being watched.
+const viewerBacklog = 256
+
+// BoardState is everything a spectator is shown of a board.
+type BoardState struct {
+	Cells    [][]int  // Locked block at each row and column, with row 0 at the bottom
+	Active   [][2]int // Row and column of each cell of the active piece
+	Piece    int      // Kind of the active piece, in the piece set
+	Next     []int    // Pieces coming up, next first
+	Pieces   string   // Name of the piece set Next is from
+	Score    int
+	GameOver bool
+}
+
+// Frame is a single update sent to spectators, one JSON object per line.
+// The first frame a spectator receives is Full and lists every cell of the
+// board; the ones after it only list the cells that changed. The remaining
+// fields are small and are sent in every frame.
+type Frame struct {
+	Full     bool     `json:"full,omitempty"`
+	Rows     int      `json:"rows,omitempty"` // Size of the board, in full frames only
+	Cols     int      `json:"cols,omitempty"`
+	Cells    [][3]int `json:"cells,omitempty"` // Row, column and block of each cell sent
+	Active   [][2]int `json:"active"`
+	Piece    int      `json:"piece"`
+	Next     []int    `json:"next"`
+	Pieces   string   `json:"pieces,omitempty"`
+	Score    int      `json:"score"`
+	GameOver bool     `json:"over,omitempty"`
+}
+
+// fullFrame returns the frame that brings a new spectator up to date.
+func fullFrame(s BoardState) Frame {
+	f := Frame{Full: true, Rows: len(s.Cells), Active: s.Active, Piece: s.Piece, Next: s.Next, Pieces: s.Pieces, Score: s.Score, GameOver: s.GameOver}
+	if f.Rows > 0 {
+		f.Cols = len(s.Cells[0])
+	}
+	for r, row := range s.Cells {
+		for c, block := range row {
+			if block != 0 {
+				f.Cells = append(f.Cells, [3]int{r, c, block})
+			}
+		}
+	}
+	return f
+}
+
+// diffFrame returns the frame that turns prev into s, and false if nothing
+// changed.
+func diffFrame(prev, s BoardState) (Frame, bool) {
+	f := Frame{Active: s.Active, Piece: s.Piece, Next: s.Next, Pieces: s.Pieces, Score: s.Score, GameOver: s.GameOver}
+	for r, row := range s.Cells {
+		for c, block := range row {
+			if block != prev.Cells[r][c] {
+				f.Cells = append(f.Cells, [3]int{r, c, block})
+			}
+		}
+	}
+	changed := len(f.Cells) > 0 || s.Score != prev.Score || s.GameOver != prev.GameOver || s.Pieces != prev.Pieces || s.Piece != prev.Piece ||
+		!equalInts(s.Next, prev.Next) || len(s.Active) != len(prev.Active)
+	for i := 0; !changed && i < len(s.Active); i++ {
+		changed = s.Active[i] != prev.Active[i]
+	}
+	return f, changed
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// apply updates the state with a frame received from a Stream.
+func (s *BoardState) apply(f Frame) error {
+	if f.Full {
+		s.Cells = make([][]int, f.Rows)
+		for r := range s.Cells {
+			s.Cells[r] = make([]int, f.Cols)
+		}
+	}
+	for _, cell := range f.Cells {
+		r, c := cell[0], cell[1]
+		if r < 0 || r >= len(s.Cells) || c < 0 || c >= len(s.Cells[r]) {
+			return errors.New("netplay: frame cell outside of the board")
+		}
+		s.Cells[r][c] = cell[2]
+	}
+	s.Active = f.Active
+	s.Piece = f.Piece
+	s.Next = f.Next
+	s.Pieces = f.Pieces
+	s.Score = f.Score
+	s.GameOver = f.GameOver
+	return nil
+}
+
+// Stream serves the board of a running game to spectators connecting over
+// TCP.
+type Stream struct {
+	ln net.Listener
+
+	mu        sync.Mutex
+	last      BoardState
+	published bool
+	viewers   map[chan Frame]struct{}
+}
+
+// ListenStream starts serving spectators on addr.
+func ListenStream(addr string) (*Stream, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Stream{ln: ln, viewers: make(map[chan Frame]struct{})}
+	go s.accept()
+	return s, nil
+}
+
+// Addr returns the address spectators connect to.
+func (s *Stream) Addr() net.Addr {
+	return s.ln.Addr()
+}
+
+// Close disconnects every spectator and stops accepting new ones.
+func (s *Stream) Close() error {
+	err := s.ln.Close()
+	s.mu.Lock()
+	for frames := range s.viewers {
+		close(frames)
+		delete(s.viewers, frames)
+	}
+	s.mu.Unlock()
+	return err
+}
+
+// Publish sends the current state of the board to every spectator. It never
+// blocks on the network. The stream keeps state, so it must not be changed
+// afterwards.
+func (s *Stream) Publish(state BoardState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f := fullFrame(state)
+	if s.published {
+		var changed bool
+		if f, changed = diffFrame(s.last, state); !changed {
+			return
+		}
+	}
+	s.last = state
+	s.published = true
+	for frames := range s.viewers {
+		select {
+		case frames <- f:
+		default:
+			close(frames)
+			delete(s.viewers, frames)
+		}
+	}
+}
+
+func (s *Stream) accept() {
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		frames := make(chan Frame, viewerBacklog)
+		s.mu.Lock()
+		if s.published {
+			frames <- fullFrame(s.last)
+		}
+		s.viewers[frames] = struct{}{}
+		s.mu.Unlock()
+		go s.send(c, frames)
+	}
+}
+
+// send writes frames to a spectator until it falls behind or goes away.
+func (s *Stream) send(c net.Conn, frames chan Frame) {
+	defer c.Close()
+	enc := json.NewEncoder(c)
+	for f := range frames {
+		if err := enc.Encode(f); err != nil {
+			s.mu.Lock()
+			if _, ok := s.viewers[frames]; ok {
+				close(frames)
+				delete(s.viewers, frames)
+			}
+			s.mu.Unlock()
+			return
+		}
+	}
+}
+
+// Viewer follows a board served by a Stream.
+type Viewer struct {
+	c net.Conn
+
+	mu    sync.Mutex
+	state BoardState
+	err   error
+}
+
+// Watch connects to the Stream at addr. Frames are applied to the viewer in
+// the background as they arrive.
+func Watch(addr string) (*Viewer, error) {
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	v := &Viewer{c: c}
+	go v.receive()
+	return v, nil
+}
+
+func (v *Viewer) receive() {
+	dec := json.NewDecoder(bufio.NewReader(v.c))
+	for {
+		var f Frame
+		err := dec.Decode(&f)
+		v.mu.Lock()
+		if err == nil {
+			err = v.state.apply(f)
+		}
+		v.err = err
+		v.mu.Unlock()
+		if err != nil {
+			v.c.Close()
+			return
+		}
+	}
+}
+
+// State returns a copy of the board as last received, and the error that
+// ended the stream if it has ended. Cells is empty until the first frame
+// arrives.
+func (v *Viewer) State() (BoardState, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s := v.state
+	s.Cells = make([][]int, len(v.state.Cells))
+	for r, row := range v.state.Cells {
+		s.Cells[r] = append([]int(nil), row...)
+	}
+	return s, v.err
+}
+
+func (v *Viewer) Close() error {
+	return v.c.Close()
+}
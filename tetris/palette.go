@@ -0,0 +1,194 @@
+package tetris
+
+import (
+	"image/color"
+	"strings"
+
+	"github.com/yankooo/tetris-go/tetris/spritesheet"
+)
+
+// Palette is a set of piece colours that players with a colour vision
+// deficiency can tell apart.
+type Palette string
+
+// Various palettes
+const (
+	StandardPalette Palette = ""              // The colours of the theme
+	Deuteranopia    Palette = "deuteranopia"  // Weak green perception
+	Protanopia      Palette = "protanopia"    // Weak red perception
+	Tritanopia      Palette = "tritanopia"    // Weak blue perception
+	HighContrast    Palette = "high-contrast" // Saturated colours far apart in lightness
+)
+
+// next returns the palette that follows p when cycling through them.
+func (p Palette) next() Palette {
+	switch p {
+	case StandardPalette:
+		return Deuteranopia
+	case Deuteranopia:
+		return Protanopia
+	case Protanopia:
+		return Tritanopia
+	case Tritanopia:
+		return HighContrast
+	}
+	return StandardPalette
+}
+
+// paletteColors gives the colour of each block in every palette but the
+// standard one. Each is chosen for the tetromino drawn in that block.
+var paletteColors = map[Palette]map[Block]color.RGBA{
+	// The Okabe-Ito palette
+	Deuteranopia: {
+		Siniy:   {0x56, 0xb4, 0xe9, 0xff},
+		Green:   {0x00, 0x72, 0xb2, 0xff},
+		Goluboy: {0xe6, 0x9f, 0x00, 0xff},
+		Pink:    {0xf0, 0xe4, 0x42, 0xff},
+		Red:     {0x00, 0x9e, 0x73, 0xff},
+		Purple:  {0xcc, 0x79, 0xa7, 0xff},
+		Yellow:  {0xd5, 0x5e, 0x00, 0xff},
+	},
+	// Reds look dark to protanopes, so none of these rely on red alone
+	Protanopia: {
+		Siniy:   {0x64, 0x8f, 0xff, 0xff},
+		Green:   {0x1b, 0x3a, 0x8c, 0xff},
+		Goluboy: {0xfe, 0x61, 0x00, 0xff},
+		Pink:    {0xff, 0xb0, 0x00, 0xff},
+		Red:     {0x00, 0x9e, 0x73, 0xff},
+		Purple:  {0x78, 0x5e, 0xf0, 0xff},
+		Yellow:  {0xdc, 0x26, 0x7f, 0xff},
+	},
+	// Blues and yellows are confused, so reds, greens and lightness carry it
+	Tritanopia: {
+		Siniy:   {0x2c, 0xb5, 0xa6, 0xff},
+		Green:   {0x40, 0x40, 0x40, 0xff},
+		Goluboy: {0xf5, 0xf5, 0xf5, 0xff},
+		Pink:    {0xf5, 0xa9, 0xb8, 0xff},
+		Red:     {0x3c, 0x8c, 0x3c, 0xff},
+		Purple:  {0x8c, 0x1c, 0x3c, 0xff},
+		Yellow:  {0xe6, 0x3c, 0x2e, 0xff},
+	},
+	HighContrast: {
+		Siniy:   {0x00, 0xff, 0xff, 0xff},
+		Green:   {0x33, 0x66, 0xff, 0xff},
+		Goluboy: {0xff, 0x88, 0x00, 0xff},
+		Pink:    {0xff, 0xff, 0x00, 0xff},
+		Red:     {0x00, 0xff, 0x00, 0xff},
+		Purple:  {0xff, 0x00, 0xff, 0xff},
+		Yellow:  {0xff, 0x00, 0x00, 0xff},
+	},
+}
+
+// letterGlyphs are the letters stamped on the blocks of pieces when glyphs
+// are turned on.
+var letterGlyphs = map[rune]spritesheet.Glyph{
+	'A': {".#.", "#.#", "###", "#.#", "#.#"},
+	'B': {"##.", "#.#", "##.", "#.#", "##."},
+	'C': {".##", "#..", "#..", "#..", ".##"},
+	'D': {"##.", "#.#", "#.#", "#.#", "##."},
+	'E': {"###", "#..", "##.", "#..", "###"},
+	'F': {"###", "#..", "##.", "#..", "#.."},
+	'G': {".##", "#..", "#.#", "#.#", ".##"},
+	'H': {"#.#", "#.#", "###", "#.#", "#.#"},
+	'I': {"###", ".#.", ".#.", ".#.", "###"},
+	'J': {"..#", "..#", "..#", "#.#", ".#."},
+	'K': {"#.#", "#.#", "##.", "#.#", "#.#"},
+	'L': {"#..", "#..", "#..", "#..", "###"},
+	'M': {"#.#", "###", "###", "#.#", "#.#"},
+	'N': {"##.", "#.#", "#.#", "#.#", "#.#"},
+	'O': {"###", "#.#", "#.#", "#.#", "###"},
+	'P': {"##.", "#.#", "##.", "#..", "#.."},
+	'Q': {".#.", "#.#", "#.#", "##.", ".##"},
+	'R': {"##.", "#.#", "##.", "#.#", "#.#"},
+	'S': {".##", "#..", ".#.", "..#", "##."},
+	'T': {"###", ".#.", ".#.", ".#.", ".#."},
+	'U': {"#.#", "#.#", "#.#", "#.#", "###"},
+	'V': {"#.#", "#.#", "#.#", "#.#", ".#."},
+	'W': {"#.#", "#.#", "###", "###", "#.#"},
+	'X': {"#.#", "#.#", ".#.", "#.#", "#.#"},
+	'Y': {"#.#", "#.#", ".#.", ".#.", ".#."},
+	'Z': {"###", "..#", ".#.", "#..", "###"},
+}
+
+// blockGlyphs returns the letter stamped on each block of the pieces of
+// set: the first letter of the names of the pieces drawn in it. Locked
+// blocks only keep their colour, so a block shared by pieces of different
+// letters, as some of the pentominoes are, gets none.
+func blockGlyphs(set *PieceSet) map[Block]spritesheet.Glyph {
+	letters := make(map[Block]rune)
+	for _, d := range set.Pieces {
+		var letter rune
+		for _, r := range strings.ToUpper(d.Name) {
+			letter = r
+			break
+		}
+		if l, seen := letters[d.Block]; seen && l != letter {
+			letter = 0
+		}
+		letters[d.Block] = letter
+	}
+	glyphs := make(map[Block]spritesheet.Glyph)
+	for block, letter := range letters {
+		if g, ok := letterGlyphs[letter]; ok {
+			glyphs[block] = g
+		}
+	}
+	return glyphs
+}
+
+// themeStyle is a theme together with the way its blocks are styled.
+type themeStyle struct {
+	theme   *spritesheet.Theme
+	palette Palette
+	glyphs  *PieceSet // The set whose letters are stamped on the blocks, if any
+}
+
+// styledThemes holds every restyled theme made so far, so that switching
+// back and forth does not restyle the sheet again.
+var styledThemes = make(map[themeStyle]*spritesheet.Theme)
+
+// styleTheme returns t with its blocks in the colours of palette and, if
+// glyphs is set, with the letters of the pieces of that set stamped on
+// them. Special blocks are styled like their plain kind.
+func styleTheme(t *spritesheet.Theme, palette Palette, glyphs *PieceSet) *spritesheet.Theme {
+	colors := paletteColors[palette]
+	if colors == nil && glyphs == nil {
+		return t
+	}
+	key := themeStyle{t, palette, glyphs}
+	if styled, ok := styledThemes[key]; ok {
+		return styled
+	}
+	special := block2spriteIdx(GoluboySpecial) - block2spriteIdx(Goluboy)
+	spriteColors := make(map[int]color.RGBA)
+	for block, c := range colors {
+		i := block2spriteIdx(block)
+		spriteColors[i] = c
+		spriteColors[i+special] = c
+	}
+	spriteGlyphs := make(map[int]spritesheet.Glyph)
+	if glyphs != nil {
+		for block, g := range blockGlyphs(glyphs) {
+			i := block2spriteIdx(block)
+			spriteGlyphs[i] = g
+			spriteGlyphs[i+special] = g
+		}
+	}
+	styled := t.Restyle(spriteColors, spriteGlyphs)
+	styledThemes[key] = styled
+	return styled
+}
+
+// theme returns the current theme styled as the settings ask for the pieces
+// of set, with a font for their language.
+func (s Settings) theme(set *PieceSet) *spritesheet.Theme {
+	var glyphs *PieceSet
+	if s.Glyphs {
+		glyphs = set
+	}
+	t := styleTheme(currentTheme(), s.Palette, glyphs)
+	if s.Language.needsFont() {
+		t = languageFont(t, s.Font)
+	}
+	return t
+}
@@ -0,0 +1,39 @@
+package tetris
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yankooo/tetris-go/tetris/spritesheet"
+)
+
+func TestBlockGlyphs(t *testing.T) {
+	pentominoes, err := builtinPieceSet("pentomino")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		set  *PieceSet
+		want map[Block]spritesheet.Glyph
+	}{
+		{Tetrominoes, map[Block]spritesheet.Glyph{
+			Siniy:   letterGlyphs['I'],
+			Green:   letterGlyphs['J'],
+			Goluboy: letterGlyphs['L'],
+			Pink:    letterGlyphs['O'],
+			Red:     letterGlyphs['S'],
+			Purple:  letterGlyphs['T'],
+			Yellow:  letterGlyphs['Z'],
+		}},
+		// The other blocks are shared by pieces of different letters
+		{pentominoes, map[Block]spritesheet.Glyph{
+			Yellow: letterGlyphs['T'],
+			Green:  letterGlyphs['U'],
+		}},
+	}
+	for _, tt := range tests {
+		if got := blockGlyphs(tt.set); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.set.Name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package tetris
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// builtinPieceFiles hold the piece sets built into the binary, one JSON file
+// each in the format read by LoadPieceSet.
+//
+//go:embed pieces/*.json
+var builtinPieceFiles embed.FS
+
+// PieceDef describes a kind of piece: the cells it covers in each rotation
+// state, where it appears, its colour and how it is kicked.
+type PieceDef struct {
+	Name   string
+	States []Shape // Cells of each rotation state, clockwise from the one it spawns in
+	Spawn  int     // Rows the spawn state is moved up by when the piece appears
+	Block  Block   // Colour of its blocks
+	Kicks  Kicks
+	TSpin  bool // Whether it can make T-spins, checked around its second cell
+}
+
+// Kicks are the offsets, as rows and columns, tried in order to fit a
+// rotated piece that collides where it is. The first is usually no offset.
+type Kicks struct {
+	Quarter []Point // For turning a quarter of the way, either way
+	Half    []Point // For turning half way
+}
+
+// defaultKicks are used by piece sets that have no kick table of their own:
+// a quarter turn is tried where it is, then right, left or down by one. A
+// piece flipped on the floor or against a stack ends up a row lower, so a
+// half turn tries lifting it by one first.
+var defaultKicks = Kicks{
+	Quarter: []Point{{0, 0}, {0, 1}, {0, -1}, {-1, 0}},
+	Half:    []Point{{0, 0}, {1, 0}, {0, 1}, {0, -1}, {-1, 0}},
+}
+
+// PieceSet is the pieces a game is played with. A Piece is the index of its
+// PieceDef in Pieces.
+type PieceSet struct {
+	Name   string
+	Pieces []PieceDef
+}
+
+// Tetrominoes is the set of the seven classic pieces, in the order of the
+// Piece constants.
+var Tetrominoes = mustBuiltinPieceSet("tetromino")
+
+// BuiltinPieceSets returns the names of the piece sets built into the
+// binary, such as tetromino, tromino, pentomino and big.
+func BuiltinPieceSets() []string {
+	entries, err := builtinPieceFiles.ReadDir("pieces")
+	if err != nil {
+		panic(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// pieceSets caches the piece sets read so far by name or path, so that
+// boards of the same game share one.
+var pieceSets = map[string]*PieceSet{}
+
+// LookupPieceSet returns the piece set built in under name, or else the one
+// in the file at name. An empty name is the tetrominoes.
+func LookupPieceSet(name string) (*PieceSet, error) {
+	if name == "" {
+		return Tetrominoes, nil
+	}
+	if s, ok := pieceSets[name]; ok {
+		return s, nil
+	}
+	s, err := builtinPieceSet(name)
+	if err != nil {
+		if s, err = LoadPieceSet(name); err != nil {
+			return nil, fmt.Errorf("piece set %q is not built in (%s) and cannot be read: %v",
+				name, strings.Join(BuiltinPieceSets(), ", "), err)
+		}
+	}
+	pieceSets[name] = s
+	return s, nil
+}
+
+// builtinPieceSet returns the piece set built in under name.
+func builtinPieceSet(name string) (*PieceSet, error) {
+	data, err := builtinPieceFiles.ReadFile("pieces/" + name + ".json")
+	if err != nil {
+		return nil, err
+	}
+	return parsePieceSet(data)
+}
+
+func mustBuiltinPieceSet(name string) *PieceSet {
+	s, err := builtinPieceSet(name)
+	if err != nil {
+		panic(fmt.Sprintf("piece set %s: %v", name, err))
+	}
+	return s
+}
+
+// LoadPieceSet reads the piece set in the JSON file at path.
+func LoadPieceSet(path string) (*PieceSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := parsePieceSet(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return s, nil
+}
+
+// pieceSetFile is the layout of a piece set file. Cells and offsets are
+// written as row and column, with rows counting up.
+type pieceSetFile struct {
+	Name   string     `json:"name"`
+	Kicks  *kicksFile `json:"kicks"` // For every piece without kicks of its own
+	Pieces []struct {
+		Name   string     `json:"name"`
+		Block  string     `json:"block"` // Name of a Block, such as "purple"
+		Spawn  int        `json:"spawn"`
+		TSpin  bool       `json:"tSpin"`
+		Kicks  *kicksFile `json:"kicks"`
+		States [][][2]int `json:"states"`
+	} `json:"pieces"`
+}
+
+type kicksFile struct {
+	Quarter [][2]int `json:"quarter"`
+	Half    [][2]int `json:"half"`
+}
+
+// blockNames are the names of the colours a piece can have in a piece set
+// file.
+var blockNames = map[string]Block{
+	"goluboy": Goluboy,
+	"siniy":   Siniy,
+	"pink":    Pink,
+	"purple":  Purple,
+	"red":     Red,
+	"yellow":  Yellow,
+	"green":   Green,
+	"gray":    Gray,
+}
+
+// parsePieceSet reads and checks a piece set file.
+func parsePieceSet(data []byte) (*PieceSet, error) {
+	var f pieceSetFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if len(f.Pieces) == 0 {
+		return nil, fmt.Errorf("no pieces")
+	}
+	setKicks := defaultKicks
+	if f.Kicks != nil {
+		setKicks = f.Kicks.kicks()
+	}
+	s := &PieceSet{Name: f.Name}
+	for _, p := range f.Pieces {
+		d := PieceDef{Name: p.Name, Spawn: p.Spawn, TSpin: p.TSpin, Kicks: setKicks}
+		if p.Kicks != nil {
+			d.Kicks = p.Kicks.kicks()
+		}
+		var ok bool
+		if d.Block, ok = blockNames[p.Block]; !ok {
+			return nil, fmt.Errorf("piece %s: unknown block %q", p.Name, p.Block)
+		}
+		for _, cells := range p.States {
+			d.States = append(d.States, pointsOf(cells))
+		}
+		if err := d.check(); err != nil {
+			return nil, fmt.Errorf("piece %s: %v", p.Name, err)
+		}
+		s.Pieces = append(s.Pieces, d)
+	}
+	return s, nil
+}
+
+func (k *kicksFile) kicks() Kicks {
+	return Kicks{Quarter: pointsOf(k.Quarter), Half: pointsOf(k.Half)}
+}
+
+func pointsOf(cells [][2]int) []Point {
+	ps := make([]Point, len(cells))
+	for i, c := range cells {
+		ps[i] = Point{row: c[0], col: c[1]}
+	}
+	return ps
+}
+
+// check returns an error unless the piece can be played: its states all
+// have the same number of cells, and the spawn state fits in the board when
+// moved up by Spawn. Pieces turn by stepping through their states, so they
+// need one state, which does not rotate, two or four.
+func (d *PieceDef) check() error {
+	switch len(d.States) {
+	case 1, 2, 4:
+	default:
+		return fmt.Errorf("%d rotation states, want 1, 2 or 4", len(d.States))
+	}
+	cells := len(d.States[0])
+	if cells == 0 {
+		return fmt.Errorf("no cells")
+	}
+	for i, s := range d.States {
+		if len(s) != cells {
+			return fmt.Errorf("state %d has %d cells, want %d like the first", i, len(s), cells)
+		}
+	}
+	minRow, maxRow, minCol, maxCol := shapeBounds(d.States[0])
+	if maxCol-minCol >= BoardCols {
+		return fmt.Errorf("%d columns wide, the board only has %d", maxCol-minCol+1, BoardCols)
+	}
+	if minRow+d.Spawn < 0 || maxRow+d.Spawn >= BoardRows {
+		return fmt.Errorf("spawns on rows %d to %d, outside of the board", minRow+d.Spawn, maxRow+d.Spawn)
+	}
+	if len(d.Kicks.Quarter) == 0 || len(d.Kicks.Half) == 0 {
+		return fmt.Errorf("empty kick table")
+	}
+	return nil
+}
+
+// def returns the definition of piece p.
+func (s *PieceSet) def(p Piece) *PieceDef {
+	return &s.Pieces[p]
+}
+
+// valid reports whether p is a piece of s.
+func (s *PieceSet) valid(p Piece) bool {
+	return p >= 0 && int(p) < len(s.Pieces)
+}
+
+// shape returns the cells of piece p as it spawns, for previews.
+func (s *PieceSet) shape(p Piece) Shape {
+	return s.def(p).States[0]
+}
+
+// pose is where the active piece is: which of its rotation states it is in
+// and how far the cells of that state are moved.
+type pose struct {
+	state    int
+	row, col int
+}
+
+// shape returns the cells of piece d in pose ps.
+func (d *PieceDef) shape(ps pose) Shape {
+	return moveShape(ps.row, ps.col, d.States[ps.state])
+}
+
+// turn returns the state the piece is in after the rotation action a from
+// state. Pieces with two states flip between them on either quarter turn.
+func (d *PieceDef) turn(state int, a Action) int {
+	steps := 1
+	switch a {
+	case RotateCCW:
+		steps = 3
+	case Rotate180:
+		steps = 2
+	}
+	return (state + steps) % len(d.States)
+}
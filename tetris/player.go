@@ -0,0 +1,242 @@
+package tetris
+
+import (
+	"math"
+	"strings"
+)
+
+// keyMap binds the actions a player can take to buttons.
+type keyMap struct {
+	left      button
+	right     button
+	softDrop  button
+	rotate    button
+	rotateCCW []button // Any of them rotates counter-clockwise
+	rotate180 button
+	hardDrop  button
+}
+
+// binding is a line of the controls help: the buttons that make an action
+// and what it does. Both are in English and translated when shown.
+type binding struct {
+	buttons []string
+	action  string
+}
+
+// bindings lists what the buttons of m do.
+func (m keyMap) bindings() []binding {
+	ccw := make([]string, len(m.rotateCCW))
+	for i, b := range m.rotateCCW {
+		ccw[i] = buttonName(b)
+	}
+	return []binding{
+		{[]string{buttonName(m.left), buttonName(m.right)}, "Move block"},
+		{[]string{buttonName(m.rotate)}, "Rotate block"},
+		{ccw, "Rotate left"},
+		{[]string{buttonName(m.rotate180)}, "Rotate 180"},
+		{[]string{buttonName(m.softDrop)}, "Fast fall"},
+		{[]string{buttonName(m.hardDrop)}, "Instant drop"},
+	}
+}
+
+// helpText lays bs out for the side panel in language l, one binding a line.
+func helpText(bs []binding, l Language) string {
+	var sb strings.Builder
+	sb.WriteString("\n")
+	for _, b := range bs {
+		buttons := make([]string, len(b.buttons))
+		for i, name := range b.buttons {
+			buttons[i] = l.tr(name)
+		}
+		sb.WriteString("\t" + strings.Join(buttons, "/") + " - " + l.tr(b.action) + "\n")
+	}
+	return sb.String()
+}
+
+// input is the state of the buttons a player is controlled with, such as
+// a window.
+type input interface {
+	Pressed(button) bool
+	JustPressed(button) bool
+	JustReleased(button) bool
+}
+
+// A held left or right key moves the piece once when pressed, again after
+// dasDelay seconds and then every arrDelay seconds until it is released.
+const (
+	dasDelay = 0.5
+	arrDelay = 0.1
+)
+
+// autoShift is the repeat timer of one of the left and right keys.
+type autoShift struct {
+	held   bool
+	charge float64 // Seconds until the piece moves again while held
+}
+
+// player owns a Board together with the timers and key state needed to
+// drive it from the keyboard.
+type player struct {
+	board *Board
+	keys  keyMap
+
+	gravityTimer float64
+	baseSpeed    float64
+	gravitySpeed float64
+	levelUpTimer float64
+	shiftLeft    autoShift
+	shiftRight   autoShift
+
+	onAction func(Action) // Called after every action applied to the board, may be nil
+
+	bot        Bot // Plays instead of the keyboard when set
+	botTimer   float64
+	botPlan    []Action
+	botPlanned bool // Whether botPlan is for the active piece
+
+	finesse finesse
+	stats   *Stats
+}
+
+func newPlayer(b *Board, keys keyMap) *player {
+	p := &player{board: b, keys: keys}
+	p.baseSpeed = 0.8
+	p.gravitySpeed = p.baseSpeed
+	p.levelUpTimer = levelLength
+	p.finesse.start(b)
+	p.stats = newStats()
+	b.Events().Subscribe(p.handle)
+	return p
+}
+
+// handle keeps the player's bot, finesse and stats in step with what
+// happens on its board.
+func (p *player) handle(ev Event) {
+	switch ev := ev.(type) {
+	case PieceSpawned:
+		p.finesse.start(p.board)
+	case PieceLocked:
+		p.botPlanned = false
+		p.botPlan = nil
+		p.finesse.lock(ev.Shape)
+		p.stats.lock(ev, p.board)
+	}
+}
+
+// update advances the player's board by dt seconds, applying gravity, the
+// level speed up and any keys pressed on in.
+func (p *player) update(in input, dt float64) {
+	p.advance(dt)
+	if p.bot == nil {
+		p.processKeypresses(in)
+	}
+}
+
+// advance moves the player's board on by dt seconds, applying gravity and
+// the level speed up, and lets the bot play if there is one.
+func (p *player) advance(dt float64) {
+	p.gravityTimer += dt
+	p.levelUpTimer -= dt
+	p.finesse.tick(dt)
+	p.stats.tick(dt)
+
+	if p.bot != nil && len(p.botPlan) > 0 {
+		// The bot's plan drops the piece itself, and would put it somewhere
+		// else if gravity moved it as well
+		p.gravityTimer = 0
+	} else if p.gravityTimer > p.gravitySpeed {
+		p.gravityTimer -= p.gravitySpeed
+		didCollide := p.apply(Gravity)
+		if !didCollide {
+			if p.board.isTouchingFloor() {
+				p.gravityTimer -= p.gravitySpeed
+			}
+		}
+	}
+	for _, s := range []*autoShift{&p.shiftLeft, &p.shiftRight} {
+		if s.held {
+			s.charge -= dt
+		}
+	}
+
+	if p.levelUpTimer <= 0 {
+		if p.baseSpeed > 0.2 {
+			p.baseSpeed = math.Max(p.baseSpeed-speedUpRate, 0.2)
+		}
+		p.levelUpTimer = levelLength
+		p.gravitySpeed = p.baseSpeed
+		p.stats.levelUp()
+	}
+
+	if p.bot != nil {
+		p.playBot(dt)
+	}
+}
+
+// Separated keypress handling for clarity
+func (p *player) processKeypresses(in input) {
+	p.shift(in.Pressed(p.keys.right), MoveRight, &p.shiftRight)
+	p.shift(in.Pressed(p.keys.left), MoveLeft, &p.shiftLeft)
+	if in.JustPressed(p.keys.softDrop) {
+		p.gravitySpeed = 0.08
+		if p.gravityTimer > 0.08 {
+			p.gravityTimer = 0.08
+		}
+	}
+	if in.JustReleased(p.keys.softDrop) {
+		p.gravitySpeed = p.baseSpeed
+	}
+	if in.JustPressed(p.keys.rotate) {
+		p.rotate(Rotate)
+	}
+	for _, b := range p.keys.rotateCCW {
+		if in.JustPressed(b) {
+			p.rotate(RotateCCW)
+			break
+		}
+	}
+	if in.JustPressed(p.keys.rotate180) {
+		p.rotate(Rotate180)
+	}
+	if in.JustPressed(p.keys.hardDrop) {
+		p.apply(HardDrop)
+	}
+}
+
+// rotate applies the rotation action a. Rotating on the floor restarts the
+// gravity timer so the piece can still be turned before it locks.
+func (p *player) rotate(a Action) {
+	p.apply(a)
+	if p.board.isTouchingFloor() {
+		p.gravityTimer = 0
+	}
+}
+
+// shift applies the move a while its key is held, as timed by s. Each key
+// has its own timer, so holding one does not change how the other repeats.
+func (p *player) shift(held bool, a Action, s *autoShift) {
+	switch {
+	case !held:
+		s.held = false
+	case !s.held:
+		s.held = true
+		s.charge = dasDelay
+		p.apply(a)
+	case s.charge <= 0:
+		s.charge += arrDelay
+		p.apply(a)
+	}
+}
+
+// apply performs an action on the player's board and reports it to
+// onAction. Returns whether the action locked the active piece.
+func (p *player) apply(a Action) bool {
+	p.finesse.count(a)
+	p.stats.input(a)
+	locked := p.board.Apply(a)
+	p.stats.Score = p.board.score
+	if p.onAction != nil {
+		p.onAction(a)
+	}
+	return locked
+}
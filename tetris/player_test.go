@@ -0,0 +1,141 @@
+package tetris
+
+import "testing"
+
+// tuckBot plays the first placement that moves the piece after dropping it,
+// which only lands where planned if nothing else moves the piece.
+type tuckBot struct {
+	planned Shape
+}
+
+func (t *tuckBot) Play(s BotState) []Action {
+	for _, p := range s.board().Placements() {
+		dropped := false
+		for _, a := range p.Actions {
+			if a == Gravity {
+				dropped = true
+			} else if dropped && a != HardDrop {
+				t.planned = p.Shape
+				return p.Actions
+			}
+		}
+	}
+	return nil
+}
+
+func TestBotPlanOutrunsGravity(t *testing.T) {
+	b := emptyBoard()
+	// A stack with an overhang, so that some placements move the piece
+	// after it has dropped
+	fillRows(b,
+		"..########",
+		"...#######",
+		"...#######",
+	)
+	b.AddPiece()
+	events := recordEvents(b)
+
+	bot := &tuckBot{}
+	p := newPlayer(b, defaultKeys)
+	p.bot = bot
+	// Gravity much faster than the bot plays
+	p.baseSpeed = botDelay / 5
+	p.gravitySpeed = p.baseSpeed
+	for i := 0; i < 1000; i++ {
+		p.advance(1.0 / 60)
+		for _, ev := range *events {
+			if ev, ok := ev.(PieceLocked); ok {
+				if bot.planned == nil {
+					t.Fatal("the bot found no tuck to plan")
+				}
+				if !sameCells(ev.Shape, bot.planned) {
+					t.Errorf("piece locked at %v, the bot planned %v", ev.Shape, bot.planned)
+				}
+				return
+			}
+		}
+	}
+	t.Fatal("the piece never locked")
+}
+
+// heldKeys is an input whose buttons are held down by the test.
+type heldKeys map[button]bool
+
+func (k heldKeys) Pressed(b button) bool      { return k[b] }
+func (k heldKeys) JustPressed(b button) bool  { return false }
+func (k heldKeys) JustReleased(b button) bool { return false }
+
+// moveTimes plays frames of 1/32 seconds with the keys hold returns for each
+// frame held, returning the frames on which the piece moved left and right.
+func moveTimes(t *testing.T, frames int, hold func(frame int) heldKeys) (left, right []int) {
+	t.Helper()
+	b := emptyBoard()
+	b.AddPiece()
+	place(b, OPiece, 0, 10, 3)
+	p := newPlayer(b, defaultKeys)
+	p.baseSpeed = 1000 // No gravity
+	p.gravitySpeed = p.baseSpeed
+	frame := 0
+	p.onAction = func(a Action) {
+		switch a {
+		case MoveLeft:
+			left = append(left, frame)
+		case MoveRight:
+			right = append(right, frame)
+		}
+	}
+	for ; frame < frames; frame++ {
+		p.update(hold(frame), 1.0/32)
+	}
+	return left, right
+}
+
+func TestAutoShift(t *testing.T) {
+	right := func(int) heldKeys { return heldKeys{defaultKeys.right: true} }
+	_, got := moveTimes(t, 28, right)
+	// A move on the press, one after dasDelay (16 frames) and then one
+	// every arrDelay (3.2 frames)
+	if want := []int{0, 16, 20, 23, 26}; !equalFrames(got, want) {
+		t.Errorf("holding right moved on frames %v, want %v", got, want)
+	}
+
+	// Tapping left while right is held moves left without restarting the
+	// repeat of right
+	tap := func(f int) heldKeys {
+		k := right(f)
+		if f == 17 {
+			k[defaultKeys.left] = true
+		}
+		return k
+	}
+	left, got := moveTimes(t, 28, tap)
+	if want := []int{0, 16, 20, 23, 26}; !equalFrames(got, want) {
+		t.Errorf("holding right and tapping left moved right on frames %v, want %v", got, want)
+	}
+	if want := []int{17}; !equalFrames(left, want) {
+		t.Errorf("tapping left moved left on frames %v, want %v", left, want)
+	}
+
+	// Releasing and pressing again waits the full delay again
+	release := func(f int) heldKeys {
+		if f == 18 {
+			return heldKeys{}
+		}
+		return right(f)
+	}
+	if _, got := moveTimes(t, 28, release); !equalFrames(got, []int{0, 16, 19}) {
+		t.Errorf("pressing right again moved on frames %v, want [0 16 19]", got)
+	}
+}
+
+func equalFrames(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
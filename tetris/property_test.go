@@ -0,0 +1,180 @@
+package tetris
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+// randomGame is a game to check properties on: a board of one of the built
+// in piece sets and the actions to play on it.
+type randomGame struct {
+	Seed    int64
+	Ruleset Ruleset
+	Set     string
+	Actions []Action
+}
+
+// Generate makes a game of up to a few hundred actions, mostly moves and
+// rotations so that pieces get around before they lock.
+func (randomGame) Generate(rng *rand.Rand, size int) reflect.Value {
+	sets := BuiltinPieceSets()
+	g := randomGame{Seed: rng.Int63(), Ruleset: Classic, Set: sets[rng.Intn(len(sets))]}
+	if rng.Intn(2) == 0 {
+		g.Ruleset = Guideline
+	}
+	weights := []Action{MoveLeft, MoveLeft, MoveRight, MoveRight, Rotate, RotateCCW, Rotate180, Gravity, Gravity, Gravity, HardDrop}
+	n := rng.Intn(30*size + 1)
+	for i := 0; i < n; i++ {
+		g.Actions = append(g.Actions, weights[rng.Intn(len(weights))])
+	}
+	return reflect.ValueOf(g)
+}
+
+// board returns the board g is played on, with its first piece spawned.
+func (g randomGame) board(t *testing.T) *Board {
+	set, err := LookupPieceSet(g.Set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := newRuledBoard(g.Seed, g.Ruleset, 3, set)
+	b.AddPiece()
+	return b
+}
+
+// checkBoard returns what is wrong with b, if anything: the active piece
+// must be inside the board, clear of the locked blocks and in the pose it
+// is said to be in, and no full row may be left uncleared.
+func checkBoard(b *Board) string {
+	if !b.GameOver() {
+		if b.checkCollision(b.activeShape) {
+			return "the active piece overlaps a block or is outside of the board"
+		}
+	}
+	if !sameCells(b.activeShape, b.active().shape(b.activePose)) {
+		return "the active shape is not the one of its pose"
+	}
+	for r := 0; r < BoardRows; r++ {
+		full := true
+		for c := 0; c < BoardCols; c++ {
+			if b.board[r][c] == Empty {
+				full = false
+			}
+		}
+		if full {
+			return "a full row was left on the board"
+		}
+	}
+	return ""
+}
+
+func TestRandomPlayKeepsTheBoardValid(t *testing.T) {
+	prop := func(g randomGame) bool {
+		b := g.board(t)
+		for i, a := range g.Actions {
+			if b.GameOver() {
+				break
+			}
+			b.Apply(a)
+			if problem := checkBoard(b); problem != "" {
+				t.Logf("seed %d, %s, %s: after action %d (%v): %s\n%s", g.Seed, g.Ruleset, g.Set, i, a, problem, boardText(b))
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(prop, &quick.Config{MaxCount: 300}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLockedCellsAreNeverLost(t *testing.T) {
+	// Every block of a locked piece stays on the board unless its row is
+	// cleared, so the blocks on the board are always the cells locked less
+	// a row's worth for each row cleared
+	prop := func(g randomGame) bool {
+		b := g.board(t)
+		b.events = &EventBus{}
+		locked := 0
+		b.events.Subscribe(func(ev Event) {
+			if ev, ok := ev.(PieceLocked); ok {
+				locked += len(ev.Shape)
+			}
+		})
+		for _, a := range g.Actions {
+			if b.GameOver() {
+				break
+			}
+			b.Apply(a)
+		}
+		blocks := 0
+		for _, row := range b.board {
+			for _, v := range row {
+				if v != Empty {
+					blocks++
+				}
+			}
+		}
+		if want := locked - b.lines*BoardCols; blocks != want {
+			t.Logf("seed %d, %s, %s: %d blocks on the board, want %d", g.Seed, g.Ruleset, g.Set, blocks, want)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(prop, &quick.Config{MaxCount: 200}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSameActionsSameGame(t *testing.T) {
+	prop := func(g randomGame) bool {
+		a, b := g.board(t), g.board(t)
+		for _, act := range g.Actions {
+			a.Apply(act)
+			b.Apply(act)
+		}
+		return a.board == b.board && a.score == b.score && a.activePose == b.activePose && a.nextPiece == b.nextPiece
+	}
+	if err := quick.Check(prop, &quick.Config{MaxCount: 100}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPlacementsLockWhereTheySay(t *testing.T) {
+	prop := func(g randomGame) bool {
+		b := g.board(t)
+		// Build up a stack to place on
+		for _, a := range g.Actions {
+			if b.GameOver() {
+				return true
+			}
+			b.Apply(a)
+		}
+		for _, p := range b.Placements() {
+			// The copy shares the piece generator of b, which only matters
+			// to pieces spawned after the one placed
+			try := *b
+			try.events = &EventBus{}
+			var got Shape
+			try.events.Subscribe(func(ev Event) {
+				if ev, ok := ev.(PieceLocked); ok {
+					got = ev.Shape
+				}
+			})
+			for _, a := range p.Actions {
+				if try.Apply(a) {
+					break
+				}
+			}
+			if !sameCells(got, p.Shape) {
+				t.Logf("seed %d, %s, %s: actions %v locked %v, want %v", g.Seed, g.Ruleset, g.Set, p.Actions, got, p.Shape)
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(prop, &quick.Config{MaxCount: 50}); err != nil {
+		t.Error(err)
+	}
+}
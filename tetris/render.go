@@ -0,0 +1,216 @@
+package tetris
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/faiface/pixel"
+	"github.com/yankooo/tetris-go/tetris/spritesheet"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// frameRenderer draws boards into images without a window, laid out like
+// displayBG and displayBoard lay them out and with the same sprites. It is
+// used for the frames of GIFs.
+type frameRenderer struct {
+	theme       *spritesheet.Theme
+	lang        Language
+	ghost       GhostStyle
+	ghostColor  color.RGBA
+	customGhost bool
+	scale       float64 // Pixels per layout unit
+
+	base    *image.RGBA           // The background and panels, which are the same in every frame
+	sprites map[int][]*image.RGBA // Block sprites scaled to a side, by the side in pixels
+}
+
+// newFrameRenderer returns a renderer drawing boards with the pieces of set
+// styled and worded as s asks, scale pixels per layout unit.
+func newFrameRenderer(s Settings, set *PieceSet, scale float64) *frameRenderer {
+	r := &frameRenderer{
+		theme:   s.theme(set),
+		lang:    s.Language,
+		ghost:   s.Ghost,
+		scale:   scale,
+		sprites: make(map[int][]*image.RGBA),
+	}
+	r.ghostColor, r.customGhost = s.ghostColor()
+
+	l := screenLayout
+	r.base = image.NewRGBA(image.Rect(0, 0, int(math.Round(l.width*scale)), int(math.Round(l.height*scale))))
+	draw.Draw(r.base, r.base.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	bg := r.theme.Background.(*pixel.PictureData).Image()
+	w, h := float64(bg.Bounds().Dx()), float64(bg.Bounds().Dy())
+	bgRect := pixel.R(-w/2, -h/2, w/2, h/2).Moved(pixel.V(l.width/2, l.height/2))
+	xdraw.NearestNeighbor.Scale(r.base, r.rect(bgRect), bg, bg.Bounds(), draw.Over, nil)
+	panel := image.NewUniform(r.theme.Panel)
+	for _, box := range []pixel.Rect{
+		l.fieldRect(),
+		pixel.R(-100, -15, 100, 15).Moved(l.scoreBox),
+		pixel.R(-50, -50, 50, 50).Moved(l.nextBox),
+	} {
+		draw.Draw(r.base, r.rect(box), panel, image.Point{}, draw.Over)
+	}
+	return r
+}
+
+// point returns the pixel at v, given in layout units with the origin at
+// the bottom left.
+func (r *frameRenderer) point(v pixel.Vec) image.Point {
+	return image.Pt(int(math.Round(v.X*r.scale)), int(math.Round((screenLayout.height-v.Y)*r.scale)))
+}
+
+// rect returns the pixels covered by rc, given in layout units.
+func (r *frameRenderer) rect(rc pixel.Rect) image.Rectangle {
+	return image.Rectangle{Min: r.point(pixel.V(rc.Min.X, rc.Max.Y)), Max: r.point(pixel.V(rc.Max.X, rc.Min.Y))}
+}
+
+// render draws b as the window shows it, with its score, next pieces and
+// ghost, and the game over message once it has ended.
+func (r *frameRenderer) render(b *Board) *image.RGBA {
+	l := screenLayout
+	img := image.NewRGBA(r.base.Bounds())
+	copy(img.Pix, r.base.Pix)
+
+	// The preview, as drawn by displayBG
+	r.drawPreview(img, b, b.nextPiece, l.nextBox, l.block, 4.5)
+	small := l.block / 2
+	for i, p := range b.preview {
+		r.drawPreview(img, b, p, l.preview.Sub(pixel.V(0, float64(i)*small*4.5-small)), small, 4)
+	}
+
+	r.drawGhost(img, b)
+	for row := 0; row < BoardRows-2; row++ {
+		for col := 0; col < BoardCols; col++ {
+			if val := b.board[row][col]; val != Empty {
+				r.drawBlock(img, val, l.cell(row, col), l.block)
+			}
+		}
+	}
+	for _, p := range b.activeShape {
+		if p.row < BoardRows-2 {
+			r.drawBlock(img, b.pieceBlock(b.currentPiece), l.cell(p.row, p.col), l.block)
+		}
+	}
+
+	// The text of displayText is scaled about the score heading, so it is
+	// placed the same way here
+	r.drawText(img, r.lang.tr("Score"), l.score, 2)
+	r.drawText(img, fmt.Sprint(b.score), l.score.Add(pixel.V(0, -30).Scaled(1.5)), 1.5)
+	r.drawText(img, r.lang.tr("Next Piece"), l.score.Add(l.nextLabel.Sub(l.score).Scaled(2)), 2)
+	if b.GameOver() {
+		r.drawText(img, r.lang.tr("Game Over"), l.message, 2)
+	}
+	return img
+}
+
+// drawPreview draws piece p as it spawns centred on center, as drawPreview
+// does for the window.
+func (r *frameRenderer) drawPreview(img *image.RGBA, b *Board, p Piece, center pixel.Vec, size, room float64) {
+	shape := b.set.shape(p)
+	minRow, maxRow, minCol, maxCol := shapeBounds(shape)
+	w, h := float64(maxCol-minCol+1), float64(maxRow-minRow+1)
+	if span := math.Max(w, h); span > room {
+		size *= room / span
+	}
+	for _, pt := range shape {
+		x := center.X + (float64(pt.col-minCol)+0.5-w/2)*size
+		y := center.Y + (float64(pt.row-minRow)+0.5-h/2)*size
+		r.drawBlock(img, b.pieceBlock(p), pixel.V(x, y), size)
+	}
+}
+
+// drawBlock draws the sprite of block t centred on center, size layout
+// units across.
+func (r *frameRenderer) drawBlock(img *image.RGBA, t Block, center pixel.Vec, size float64) {
+	side := int(math.Round(size * r.scale))
+	sprite := r.blockSprites(side)[block2spriteIdx(t)]
+	min := r.point(center).Sub(image.Pt(side/2, side/2))
+	draw.Draw(img, image.Rectangle{Min: min, Max: min.Add(image.Pt(side, side))}, sprite, image.Point{}, draw.Over)
+}
+
+// blockSprites returns the block sprites of the theme scaled to side pixels
+// across, scaling them the first time.
+func (r *frameRenderer) blockSprites(side int) []*image.RGBA {
+	if s, ok := r.sprites[side]; ok {
+		return s
+	}
+	s := make([]*image.RGBA, spritesheet.BlockSprites)
+	for i := range s {
+		src := r.theme.Blocks(i).(*pixel.PictureData).Image()
+		s[i] = image.NewRGBA(image.Rect(0, 0, side, side))
+		xdraw.NearestNeighbor.Scale(s[i], s[i].Bounds(), src, src.Bounds(), draw.Src, nil)
+	}
+	r.sprites[side] = s
+	return s
+}
+
+// drawGhost draws the ghost of the active piece in the style of the
+// settings, as displayGhost does.
+func (r *frameRenderer) drawGhost(img *image.RGBA, b *Board) {
+	if r.ghost == GhostOff || len(b.activeShape) == 0 {
+		return
+	}
+	c := r.ghostColor
+	if !r.customGhost {
+		c = r.theme.BlockColor(block2spriteIdx(b.pieceBlock(b.currentPiece)))
+	}
+	s := b.ghostShape()
+	if r.ghost == GhostSolid {
+		fill := image.NewUniform(fade(c, ghostSolidAlpha))
+		half := pixel.V(screenLayout.block/2, screenLayout.block/2)
+		for _, p := range s {
+			if p.row < BoardRows-2 {
+				v := screenLayout.cell(p.row, p.col)
+				draw.Draw(img, r.rect(pixel.Rect{Min: v.Sub(half), Max: v.Add(half)}), fill, image.Point{}, draw.Over)
+			}
+		}
+		return
+	}
+	line := image.NewUniform(fade(c, ghostOutlineAlpha))
+	for _, e := range outlineEdges(s) {
+		// Lines two units thick, as imdraw draws them
+		edge := pixel.R(math.Min(e[0].X, e[1].X), math.Min(e[0].Y, e[1].Y), math.Max(e[0].X, e[1].X), math.Max(e[0].Y, e[1].Y))
+		draw.Draw(img, r.rect(pixel.Rect{Min: edge.Min.Sub(pixel.V(1, 1)), Max: edge.Max.Add(pixel.V(1, 1))}), line, image.Point{}, draw.Over)
+	}
+}
+
+// fade returns c, which has premultiplied alpha, made alpha times as
+// opaque.
+func fade(c color.RGBA, alpha float64) color.RGBA {
+	return color.RGBA{
+		R: uint8(float64(c.R) * alpha),
+		G: uint8(float64(c.G) * alpha),
+		B: uint8(float64(c.B) * alpha),
+		A: uint8(float64(c.A) * alpha),
+	}
+}
+
+// drawText writes s in the font and text colour of the theme with its
+// baseline starting at dot, scaled up like text drawn in the window at the
+// given scale.
+func (r *frameRenderer) drawText(img *image.RGBA, s string, dot pixel.Vec, scale float64) {
+	face := r.theme.Face
+	bounds, _ := font.BoundString(face, s)
+	src := image.Rect(bounds.Min.X.Floor(), bounds.Min.Y.Floor(), bounds.Max.X.Ceil(), bounds.Max.Y.Ceil())
+	if src.Empty() {
+		return
+	}
+	text := image.NewRGBA(src)
+	d := font.Drawer{Dst: text, Src: image.NewUniform(r.theme.Text), Face: face, Dot: fixed.Point26_6{}}
+	d.DrawString(s)
+
+	// src is relative to the dot, so it is moved to where the dot goes
+	k := scale * r.scale
+	origin := r.point(dot)
+	dst := image.Rect(
+		origin.X+int(math.Round(float64(src.Min.X)*k)), origin.Y+int(math.Round(float64(src.Min.Y)*k)),
+		origin.X+int(math.Round(float64(src.Max.X)*k)), origin.Y+int(math.Round(float64(src.Max.Y)*k)),
+	)
+	xdraw.ApproxBiLinear.Scale(img, dst, text, src, draw.Over, nil)
+}
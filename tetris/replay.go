@@ -0,0 +1,77 @@
+package tetris
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// replayVersion is the version of the replay file format.
+const replayVersion = 1
+
+// Replay is a recording of a single player game. Since a game is decided by
+// its config and actions alone, playing the actions back on a board set up
+// the same way reproduces it exactly.
+type Replay struct {
+	Version int           `json:"version"`
+	Config  Config        `json:"config"` // The config played with, with the seed filled in
+	Actions []TimedAction `json:"actions"`
+}
+
+// TimedAction is an action made during a game.
+type TimedAction struct {
+	Time   float64 `json:"t"` // Seconds of play before the action
+	Action Action  `json:"a"`
+}
+
+// LoadReplay reads the replay saved at path.
+func LoadReplay(path string) (*Replay, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &Replay{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if r.Version != replayVersion {
+		return nil, fmt.Errorf("%s: replay version %d, want %d", path, r.Version, replayVersion)
+	}
+	if err := r.Config.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return r, nil
+}
+
+// Save writes the replay as JSON to path.
+func (r *Replay) Save(path string) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// newBoard creates a board set up the way the replay was recorded.
+func (r *Replay) newBoard() *Board {
+	b := newRuledBoard(r.Config.Seed, r.Config.Ruleset, r.Config.Preview, r.Config.pieceSet())
+	b.AddPiece()
+	return b
+}
+
+// Result plays the replay without a window and returns the board as the
+// recording ends.
+func (r *Replay) Result() *Board {
+	b := r.newBoard()
+	for _, a := range r.Actions {
+		if b.GameOver() {
+			break
+		}
+		b.Apply(a.Action)
+	}
+	return b
+}
@@ -0,0 +1,324 @@
+//go:build !js
+
+package tetris
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// saveVersion is the version of the save file format. Saves of any other
+// version are rejected.
+const saveVersion = 3
+
+// saveKey is the key of the checksum of saves. It is public, so the checksum
+// is an integrity check that catches damaged and hand edited saves, not a
+// signature: anyone can compute it for a forged save.
+var saveKey = []byte("tetris-go save v1")
+
+// ErrBadSave is returned when a save file fails its integrity check, holds an
+// impossible game or was written by an incompatible version of the game.
+var ErrBadSave = errors.New("tetris: save file is invalid")
+
+// saveFile is the layout of a save file on disk. Checksum is an HMAC of
+// Game checking its integrity, which is kept raw so it is checked byte for
+// byte.
+type saveFile struct {
+	Version  int             `json:"version"`
+	Checksum string          `json:"checksum"`
+	Game     json.RawMessage `json:"game"`
+}
+
+// savedGame is the state of a single player game in progress.
+type savedGame struct {
+	Seed         int64                       `json:"seed"`
+	PieceDraws   int64                       `json:"pieceDraws"`   // Numbers drawn by the piece generator
+	GarbageDraws int64                       `json:"garbageDraws"` // Numbers drawn by the garbage generator
+	Cells        [BoardRows][BoardCols]Block `json:"cells"`        // Locked blocks, without the active piece
+	PieceSet     string                      `json:"pieceSet"`     // As in Config
+	Piece        Piece                       `json:"piece"`
+	Active       [3]int                      `json:"active"` // Rotation state of the active piece and how far it is moved
+	Next         Piece                       `json:"next"`
+	Preview      []Piece                     `json:"preview"` // Pieces after Next
+	Ruleset      Ruleset                     `json:"ruleset"`
+	Bag          []Piece                     `json:"bag"` // Pieces left in the bag of the guideline ruleset
+	Mode         Mode                        `json:"mode"`
+	LastRotated  bool                        `json:"lastRotated"`
+	Score        int                         `json:"score"`
+	Lines        int                         `json:"lines"`
+	Pieces       int                         `json:"pieces"`
+	Garbage      []int                       `json:"pendingGarbage"`
+	Attack       int                         `json:"outgoingAttack"`
+
+	BaseSpeed    float64 `json:"baseSpeed"`
+	GravitySpeed float64 `json:"gravitySpeed"`
+	GravityTimer float64 `json:"gravityTimer"`
+	LevelUpTimer float64 `json:"levelUpTimer"`
+	Faults       int     `json:"finesseFaults"`
+	Stats        *Stats  `json:"stats"`
+	Combo        int     `json:"combo"`
+
+	Ranked   bool `json:"ranked"`
+	Assisted bool `json:"assisted"`
+}
+
+// savePath returns where the game in progress is saved.
+func savePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tetris-go", "save.json"), nil
+}
+
+// checksum returns the integrity checksum of the encoded game, data.
+func checksum(data []byte) string {
+	mac := hmac.New(sha256.New, saveKey)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// saveGame captures the state of g.
+func (g *tetrisGame) saveGame() savedGame {
+	b := g.board
+	p := g.player
+	state := b.botState()
+	s := savedGame{
+		Seed:         b.seed,
+		PieceDraws:   b.pieceSrc.draws,
+		GarbageDraws: b.garbageSrc.draws,
+		Cells:        state.Cells,
+		PieceSet:     g.config.PieceSet,
+		Piece:        b.currentPiece,
+		Active:       [3]int{b.activePose.state, b.activePose.row, b.activePose.col},
+		Next:         b.nextPiece,
+		Preview:      b.preview,
+		Ruleset:      b.ruleset,
+		Bag:          b.bag,
+		Mode:         g.config.Mode,
+		LastRotated:  b.lastRotated,
+		Score:        b.score,
+		Lines:        b.lines,
+		Pieces:       b.pieces,
+		Garbage:      b.pendingGarbage,
+		Attack:       b.outgoingAttack,
+		BaseSpeed:    p.baseSpeed,
+		GravitySpeed: p.gravitySpeed,
+		GravityTimer: p.gravityTimer,
+		LevelUpTimer: p.levelUpTimer,
+		Faults:       p.finesse.faults,
+		Stats:        p.stats,
+		Combo:        p.stats.Combo,
+		Ranked:       g.config.Ranked,
+		Assisted:     g.assisted,
+	}
+	return s
+}
+
+// restore puts g back in the state it was saved in. g must be initialized.
+func (g *tetrisGame) restore(s savedGame) {
+	b := NewSeededBoard(s.Seed)
+	b.pieceSrc.skip(s.PieceDraws)
+	b.garbageSrc.skip(s.GarbageDraws)
+	b.board = s.Cells
+	b.set = s.config().pieceSet()
+	b.useSettings(g.settings)
+	b.currentPiece = s.Piece
+	b.setActive(s.pose())
+	b.nextPiece = s.Next
+	b.preview = s.Preview
+	b.ruleset = s.Ruleset
+	b.bag = s.Bag
+	b.lastRotated = s.LastRotated
+	b.score = s.Score
+	b.lines = s.Lines
+	b.pieces = s.Pieces
+	b.pendingGarbage = s.Garbage
+	b.outgoingAttack = s.Attack
+
+	g.board = b
+	g.hint = hint{}
+	g.player = newPlayer(b, defaultKeys)
+	g.player.bot = g.bot
+	g.player.baseSpeed = s.BaseSpeed
+	g.player.gravitySpeed = s.GravitySpeed
+	g.player.gravityTimer = s.GravityTimer
+	g.player.levelUpTimer = s.LevelUpTimer
+	g.player.finesse.faults = s.Faults
+	if s.Stats != nil {
+		g.player.stats = s.Stats
+		g.player.stats.Combo = s.Combo
+		g.player.stats.Holes = countHoles(&s.Cells)
+	}
+	g.config.Ranked = s.Ranked
+	g.config.Mode = s.Mode
+	g.config.Ruleset = s.Ruleset
+	g.config.Preview = 1 + len(s.Preview)
+	g.config.PieceSet = s.PieceSet
+	g.assisted = s.Assisted
+}
+
+// config returns the options of the game that the save keeps.
+func (s savedGame) config() Config {
+	c := DefaultConfig()
+	c.Mode = s.Mode
+	c.Ruleset = s.Ruleset
+	c.Preview = 1 + len(s.Preview)
+	c.PieceSet = s.PieceSet
+	return c
+}
+
+// pose returns where the active piece is.
+func (s savedGame) pose() pose {
+	return pose{state: s.Active[0], row: s.Active[1], col: s.Active[2]}
+}
+
+// valid checks that the saved state could have come from a game.
+func (s savedGame) valid() bool {
+	c := s.config()
+	if c.Validate() != nil {
+		return false
+	}
+	set := c.pieceSet()
+	pieces := append([]Piece{s.Piece, s.Next}, s.Preview...)
+	for _, p := range append(pieces, s.Bag...) {
+		if !set.valid(p) {
+			return false
+		}
+	}
+	if s.PieceDraws < 0 || s.GarbageDraws < 0 {
+		return false
+	}
+	if s.Score < 0 || s.Lines < 0 || s.Pieces < 0 || s.Attack < 0 || s.Faults < 0 || s.Combo < 0 {
+		return false
+	}
+	if s.BaseSpeed <= 0 || s.GravitySpeed <= 0 {
+		return false
+	}
+	for _, rows := range s.Garbage {
+		if rows < 1 || rows > BoardRows {
+			return false
+		}
+	}
+	def := set.def(s.Piece)
+	if ps := s.pose(); ps.state < 0 || ps.state >= len(def.States) {
+		return false
+	}
+	for _, pt := range def.shape(s.pose()) {
+		r, c := pt.row, pt.col
+		if r < 0 || r >= BoardRows || c < 0 || c >= BoardCols || s.Cells[r][c] != Empty {
+			return false
+		}
+	}
+	for _, row := range s.Cells {
+		for _, cell := range row {
+			if cell < Empty || cell > GraySpecial {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// writeSave saves s to path.
+func writeSave(path string, s savedGame) error {
+	game, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(saveFile{Version: saveVersion, Checksum: checksum(game), Game: game})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// readSave loads the save at path. Returns ErrBadSave if the save fails its
+// integrity check, holds an impossible game or is of another version.
+func readSave(path string) (savedGame, error) {
+	var s savedGame
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	var f saveFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return s, fmt.Errorf("%w: %v", ErrBadSave, err)
+	}
+	if f.Version != saveVersion {
+		return s, fmt.Errorf("%w: version %d, want %d", ErrBadSave, f.Version, saveVersion)
+	}
+	if !hmac.Equal([]byte(f.Checksum), []byte(checksum(f.Game))) {
+		return s, fmt.Errorf("%w: checksum mismatch", ErrBadSave)
+	}
+	if err := json.Unmarshal(f.Game, &s); err != nil {
+		return s, fmt.Errorf("%w: %v", ErrBadSave, err)
+	}
+	// Cells are read into an array, which would quietly take a board of
+	// another size
+	var dims struct {
+		Cells [][]Block `json:"cells"`
+	}
+	if err := json.Unmarshal(f.Game, &dims); err != nil {
+		return s, fmt.Errorf("%w: %v", ErrBadSave, err)
+	}
+	if !boardSized(dims.Cells) {
+		return s, fmt.Errorf("%w: board is not %dx%d", ErrBadSave, BoardRows, BoardCols)
+	}
+	if !s.valid() {
+		return s, fmt.Errorf("%w: impossible game state", ErrBadSave)
+	}
+	return s, nil
+}
+
+// boardSized reports whether cells has the rows and columns of a board.
+func boardSized(cells [][]Block) bool {
+	if len(cells) != BoardRows {
+		return false
+	}
+	for _, row := range cells {
+		if len(row) != BoardCols {
+			return false
+		}
+	}
+	return true
+}
+
+// loadSave reads the saved game, if there is one.
+func loadSave() (savedGame, bool, error) {
+	path, err := savePath()
+	if err != nil {
+		return savedGame{}, false, err
+	}
+	s, err := readSave(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, false, nil
+	}
+	if err != nil {
+		return s, false, err
+	}
+	return s, true, nil
+}
+
+// removeSave deletes the saved game so it cannot be continued twice.
+func removeSave() error {
+	path, err := savePath()
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,173 @@
+//go:build !js
+
+package tetris
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// savedBotGame returns a game the reference bot has played a few pieces
+// of, with garbage waiting to rise.
+func savedBotGame(t *testing.T) *tetrisGame {
+	t.Helper()
+	g := &tetrisGame{config: DefaultConfig(), settings: DefaultSettings()}
+	g.board = NewSeededBoard(11)
+	g.board.AddPiece()
+	g.player = newPlayer(g.board, defaultKeys)
+	playHeadless(g.board, NewHeuristicBot(), 12)
+	g.board.QueueGarbage(2)
+	return g
+}
+
+func TestSaveRoundTrip(t *testing.T) {
+	g := savedBotGame(t)
+	path := filepath.Join(t.TempDir(), "save.json")
+	if err := writeSave(path, g.saveGame()); err != nil {
+		t.Fatal(err)
+	}
+	s, err := readSave(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := &tetrisGame{config: DefaultConfig(), settings: DefaultSettings()}
+	loaded.restore(s)
+	if got, want := boardText(loaded.board), boardText(g.board); got != want {
+		t.Fatalf("restored board:\n%s\nwant:\n%s", got, want)
+	}
+
+	// The restored game goes on as the saved one would have
+	playHeadless(g.board, NewHeuristicBot(), 20)
+	playHeadless(loaded.board, NewHeuristicBot(), 20)
+	if got, want := boardText(loaded.board), boardText(g.board); got != want {
+		t.Errorf("restored game played on to:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// forge writes a save of g changed by edit to path, with a checksum that
+// matches the changed game.
+func forge(t *testing.T, path string, g *tetrisGame, edit func(game map[string]any)) {
+	t.Helper()
+	data, err := json.Marshal(g.saveGame())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var game map[string]any
+	if err := json.Unmarshal(data, &game); err != nil {
+		t.Fatal(err)
+	}
+	edit(game)
+	if data, err = json.Marshal(game); err != nil {
+		t.Fatal(err)
+	}
+	f, err := json.Marshal(saveFile{Version: saveVersion, Checksum: checksum(data), Game: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, f, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSaveTampered(t *testing.T) {
+	g := savedBotGame(t)
+	path := filepath.Join(t.TempDir(), "save.json")
+	if err := writeSave(path, g.saveGame()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	score := []byte(`"score":`)
+	i := bytes.Index(data, score) + len(score)
+	edited := append(append(append([]byte{}, data[:i]...), '9'), data[i:]...)
+	if err := os.WriteFile(path, edited, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readSave(path); !errors.Is(err, ErrBadSave) {
+		t.Errorf("edited score: got %v, want ErrBadSave", err)
+	}
+}
+
+func TestSaveImpossible(t *testing.T) {
+	g := savedBotGame(t)
+	path := filepath.Join(t.TempDir(), "save.json")
+	for _, tt := range []struct {
+		name string
+		edit func(game map[string]any)
+	}{
+		{"unchanged", func(map[string]any) {}},
+		{"short row", func(game map[string]any) {
+			cells := game["cells"].([]any)
+			cells[0] = cells[0].([]any)[1:]
+		}},
+		{"extra row", func(game map[string]any) {
+			cells := game["cells"].([]any)
+			game["cells"] = append(cells, cells[0])
+		}},
+		{"missing rows", func(game map[string]any) {
+			game["cells"] = game["cells"].([]any)[:BoardRows-1]
+		}},
+		{"unknown block", func(game map[string]any) {
+			game["cells"].([]any)[0].([]any)[0] = int(GraySpecial) + 1
+		}},
+		{"negative garbage", func(game map[string]any) {
+			game["pendingGarbage"] = []int{-3}
+		}},
+		{"too much garbage", func(game map[string]any) {
+			game["pendingGarbage"] = []int{BoardRows + 1}
+		}},
+		{"negative score", func(game map[string]any) { game["score"] = -1 }},
+		{"no gravity", func(game map[string]any) { game["gravitySpeed"] = 0 }},
+		{"piece in the stack", func(game map[string]any) {
+			game["active"] = []int{0, -5, 0}
+		}},
+	} {
+		forge(t, path, g, tt.edit)
+		_, err := readSave(path)
+		if tt.name == "unchanged" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+		} else if !errors.Is(err, ErrBadSave) {
+			t.Errorf("%s: got %v, want ErrBadSave", tt.name, err)
+		}
+	}
+}
+
+func TestEndSavesOnlyUnfinished(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		lines     int
+		wantSave  bool
+		wantStats bool
+	}{
+		{"closed mid sprint", sprintLines - 1, true, false},
+		{"finished sprint", sprintLines, false, true},
+	} {
+		dir := t.TempDir()
+		t.Setenv("XDG_CONFIG_HOME", dir)
+		t.Setenv("HOME", dir)
+		g := savedBotGame(t)
+		g.config.Mode = Sprint
+		g.config.StatsPath = filepath.Join(dir, "stats.json")
+		g.board.lines = tt.lines
+		g.end()
+
+		path, err := savePath()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(path); (err == nil) != tt.wantSave {
+			t.Errorf("%s: save left behind: %v, want %v", tt.name, err == nil, tt.wantSave)
+		}
+		if _, err := os.Stat(g.config.StatsPath); (err == nil) != tt.wantStats {
+			t.Errorf("%s: stats written: %v, want %v", tt.name, err == nil, tt.wantStats)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package tetris
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// Settings are the player's preferences, kept between games in a file in
+// the user's configuration directory.
+type Settings struct {
+	ShowHint        bool            `json:"showHint"`        // Outline the best placement of the current piece
+	FinesseTraining FinesseTraining `json:"finesseTraining"` // What a finesse fault does
+	ShowStats       bool            `json:"showStats"`       // Show live stats in place of the controls
+	Palette         Palette         `json:"palette"`         // Colours of the pieces
+	Glyphs          bool            `json:"glyphs"`          // Stamp the letter of each piece on its blocks
+	Language        Language        `json:"language"`        // Language of the text, English if empty
+	Font            string          `json:"font"`            // Font to write the language in, if the theme's lacks its characters
+	Ghost           GhostStyle      `json:"ghost"`           // How the shadow of where the piece lands is drawn
+	GhostColor      string          `json:"ghostColor"`      // Colour of the ghost as #rrggbb or #rrggbbaa, or empty for the piece's colour
+}
+
+// DefaultSettings returns the settings used until the player changes them.
+func DefaultSettings() Settings {
+	return Settings{}
+}
+
+// savedSettings returns the saved settings, for games that only use them to
+// style the board.
+func savedSettings() Settings {
+	s, err := LoadSettings()
+	if err != nil {
+		log.Printf("using default settings: %v", err)
+		s = DefaultSettings()
+	}
+	return s
+}
+
+// settingsPath returns where the settings file is kept.
+func settingsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tetris-go", "settings.json"), nil
+}
+
+// LoadSettings reads the saved settings. If none have been saved yet the
+// default settings are returned.
+func LoadSettings() (Settings, error) {
+	s := DefaultSettings()
+	path, err := settingsPath()
+	if err != nil {
+		return s, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	err = json.Unmarshal(data, &s)
+	return s, err
+}
+
+// Save writes the settings so they are loaded by the next game.
+func (s Settings) Save() error {
+	path, err := settingsPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
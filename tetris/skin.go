@@ -0,0 +1,71 @@
+package tetris
+
+import (
+	"image/color"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/text"
+	"github.com/yankooo/tetris-go/tetris/spritesheet"
+)
+
+// skin is a theme made ready to draw: its block sprites cut out of the
+// sheet once, its panels and the atlas of its font. Building these is slow,
+// so each theme gets one skin shared by every board drawn with it.
+type skin struct {
+	theme  *spritesheet.Theme
+	blocks [spritesheet.BlockSprites]*pixel.Sprite // Framed on theme.Sheet, so they can share a batch
+	colors [spritesheet.BlockSprites]color.RGBA    // Average colour of each block sprite
+	scale  float64                                 // Scales a block sprite to a block of the layout
+
+	background *pixel.Sprite
+	field      *pixel.Sprite
+	scoreBox   *pixel.Sprite
+	nextBox    *pixel.Sprite
+
+	atlas *text.Atlas
+}
+
+// skins holds the skin of every theme drawn so far. Drawing only happens on
+// the main thread, so it needs no lock.
+var skins = make(map[*spritesheet.Theme]*skin)
+
+// skinFor returns the skin of t, building it the first time.
+func skinFor(t *spritesheet.Theme) *skin {
+	if s, ok := skins[t]; ok {
+		return s
+	}
+	s := &skin{
+		theme:      t,
+		scale:      screenLayout.block / t.Frames[0].W(),
+		background: pixel.NewSprite(t.Background, t.Background.Bounds()),
+		atlas:      text.NewAtlas(t.Face, text.ASCII, coveredRunes(t.Face, catalogRunes())),
+	}
+	for i := range s.blocks {
+		s.blocks[i] = pixel.NewSprite(t.Sheet, t.Frames[i])
+		s.colors[i] = t.BlockColor(i)
+	}
+
+	field := screenLayout.fieldRect()
+	s.field = solidSprite(int(field.W()), int(field.H()), t)
+	s.scoreBox = solidSprite(200, 30, t)
+	s.nextBox = solidSprite(100, 100, t)
+
+	skins[t] = s
+	return s
+}
+
+// solidSprite returns a w by h sprite in the panel colour of t.
+func solidSprite(w, h int, t *spritesheet.Theme) *pixel.Sprite {
+	pic := spritesheet.SolidPicture(w, h, t.Panel)
+	return pixel.NewSprite(pic, pic.Bounds())
+}
+
+// block returns the sprite of block t.
+func (s *skin) block(t Block) *pixel.Sprite {
+	return s.blocks[block2spriteIdx(t)]
+}
+
+// color returns the average colour of the sprite of block t.
+func (s *skin) color(t Block) color.RGBA {
+	return s.colors[block2spriteIdx(t)]
+}
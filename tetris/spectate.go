@@ -0,0 +1,76 @@
+package tetris
+
+import "github.com/yankooo/tetris-go/tetris/netplay"
+
+// spectatorState captures the board as shown to spectators.
+func (b *Board) spectatorState() netplay.BoardState {
+	s := netplay.BoardState{
+		Cells:    make([][]int, BoardRows),
+		Active:   make([][2]int, len(b.activeShape)),
+		Piece:    int(b.currentPiece),
+		Next:     make([]int, 0, 1+len(b.preview)),
+		Pieces:   b.set.Name,
+		Score:    b.score,
+		GameOver: b.gameOver,
+	}
+	for r := range s.Cells {
+		s.Cells[r] = make([]int, BoardCols)
+		for c := range s.Cells[r] {
+			s.Cells[r][c] = int(b.board[r][c])
+		}
+	}
+	for i, p := range b.activeShape {
+		s.Active[i] = [2]int{p.row, p.col}
+	}
+	for _, p := range append([]Piece{b.nextPiece}, b.preview...) {
+		s.Next = append(s.Next, int(p))
+	}
+	return s
+}
+
+// loadSpectatorState makes the board look like one received from a stream.
+// Anything that does not fit this board is ignored, as are the next pieces
+// of a piece set that is not built in.
+func (b *Board) loadSpectatorState(s netplay.BoardState) {
+	if len(s.Cells) != BoardRows || len(s.Active) == 0 {
+		return
+	}
+	for r, row := range s.Cells {
+		for c := 0; c < BoardCols && c < len(row); c++ {
+			b.board[r][c] = Block(row[c])
+		}
+	}
+	active := make(Shape, len(s.Active))
+	for i, p := range s.Active {
+		if p[0] < 0 || p[0] >= BoardRows || p[1] < 0 || p[1] >= BoardCols {
+			return
+		}
+		active[i] = Point{row: p[0], col: p[1]}
+	}
+	b.activeShape = active
+	pieces := s.Pieces
+	if pieces == "" {
+		pieces = Tetrominoes.Name
+	}
+	if pieces != b.set.Name {
+		if set, err := builtinPieceSet(pieces); err == nil {
+			b.set = set
+		}
+	}
+	if b.set.valid(Piece(s.Piece)) && b.set.Name == pieces {
+		b.currentPiece = Piece(s.Piece)
+	}
+	var next []Piece
+	for _, p := range s.Next {
+		if !b.set.valid(Piece(p)) || b.set.Name != pieces {
+			break
+		}
+		next = append(next, Piece(p))
+	}
+	if len(next) > 0 {
+		b.nextPiece = next[0]
+		b.preview = next[1:]
+	}
+	b.score = s.Score
+	b.gameOver = s.GameOver
+}
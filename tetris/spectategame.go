@@ -0,0 +1,66 @@
+//go:build !js
+
+package tetris
+
+import (
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/yankooo/tetris-go/tetris/netplay"
+	"golang.org/x/image/colornames"
+)
+
+// spectatorGame shows a board streamed from another game without being able
+// to play it.
+type spectatorGame struct {
+	win      *pixelgl.Window
+	addr     string
+	viewer   *netplay.Viewer
+	board    *Board
+	settings Settings
+}
+
+func NewSpectatorGame(addr string) *spectatorGame {
+	g := &spectatorGame{addr: addr}
+	return g
+}
+
+func (g *spectatorGame) Initialize() {
+	var err error
+	g.viewer, err = netplay.Watch(g.addr)
+	if err != nil {
+		panic(err)
+	}
+	g.settings = savedSettings()
+	g.win = newWindow(windowWidth, g.settings.Language)
+	g.board = NewBoard()
+	g.board.useSettings(g.settings)
+}
+
+func (g *spectatorGame) Run() {
+	defer g.viewer.Close()
+	for !g.win.Closed() {
+		state, err := g.viewer.State()
+		set := g.board.set
+		g.board.loadSpectatorState(state)
+		if g.board.set != set {
+			// The letters stamped on the blocks depend on the pieces
+			g.board.useSettings(g.settings)
+		}
+
+		g.win.SetMatrix(fitWindow(g.win, windowWidth))
+		g.win.Clear(colornames.Black)
+		if state.Cells != nil {
+			g.board.displayBG(g.win)
+			g.board.displayText(g.win, "")
+			g.board.displayBoard(g.win)
+		}
+		switch {
+		case err != nil:
+			g.board.displayMessage(g.win, "Stream Ended")
+		case state.Cells == nil:
+			g.board.displayMessage(g.win, "Waiting...")
+		case state.GameOver:
+			g.board.displayMessage(g.win, "Game Over")
+		}
+		g.win.Update()
+	}
+}
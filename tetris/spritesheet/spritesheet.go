@@ -5,7 +5,6 @@ import (
 	"embed"
 	"fmt"
 	"image"
-	"image/color"
 	_ "image/png"
 
 	"github.com/faiface/pixel"
@@ -35,8 +34,12 @@ func InitBlock(filename string, row, col int) (func(int) pixel.Picture, error) {
 		return nil, fmt.Errorf(fmt.Sprintf("Invalid dimensions (%d, %d) for sprite sheet %s\n", row, col, filename))
 	}
 
-	tileSize := b.Max.X / col
+	return blockSheet(img, row, col, b.Max.X/col), nil
+}
 
+// blockSheet returns a function to obtain the tile by tile sprite at each
+// index of a sheet with the given rows and columns.
+func blockSheet(img image.Image, row, col, tileSize int) func(int) pixel.Picture {
 	return func(i int) pixel.Picture {
 		if i < 0 || i >= row*col {
 			panic(any("Index out of bounds for sprite sheet"))
@@ -48,7 +51,7 @@ func InitBlock(filename string, row, col int) (func(int) pixel.Picture, error) {
 			SubImage(r image.Rectangle) image.Image
 		}).SubImage(image.Rect(c*tileSize, r*tileSize, (c+1)*tileSize, (r+1)*tileSize))
 		return pixel.PictureDataFromImage(subImage)
-	}, nil
+	}
 }
 
 func LoadPicture(filename string) (pixel.Picture, error) {
@@ -63,37 +66,3 @@ func LoadPicture(filename string) (pixel.Picture, error) {
 	}
 	return pixel.PictureDataFromImage(img), nil
 }
-
-func GetPlayBGPic() pixel.Picture {
-	blackImg := image.NewRGBA(image.Rect(0, 0, 200, 400))
-	for x := 0; x < 200; x++ {
-		for y := 0; y < 400; y++ {
-			blackImg.SetRGBA(x, y, color.RGBA{0x00, 0x00, 0x00, 0xA0})
-		}
-	}
-
-	blackPic := pixel.PictureDataFromImage(blackImg)
-	return blackPic
-}
-
-func GetScoreBGPic() pixel.Picture {
-	blackImg := image.NewRGBA(image.Rect(0, 0, 200, 30))
-	for x := 0; x < 100; x++ {
-		for y := 0; y < 100; y++ {
-			blackImg.SetRGBA(x, y, color.RGBA{0x00, 0x00, 0x00, 0xA0})
-		}
-	}
-	blackPic := pixel.PictureDataFromImage(blackImg)
-	return blackPic
-}
-
-func GetNextPieceBGPic() pixel.Picture {
-	blackImg := image.NewRGBA(image.Rect(0, 0, 100, 100))
-	for x := 0; x < 100; x++ {
-		for y := 0; y < 100; y++ {
-			blackImg.SetRGBA(x, y, color.RGBA{0x00, 0x00, 0x00, 0xA0})
-		}
-	}
-	blackPic := pixel.PictureDataFromImage(blackImg)
-	return blackPic
-}
@@ -0,0 +1,165 @@
+package spritesheet
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Glyph is a small bitmap stamped in the middle of a block sprite, so the
+// block can be told apart without its colour. Each string is a row, with #
+// for the pixels that are set.
+type Glyph []string
+
+// glyphInk is how strongly a glyph covers the sprite under it.
+const glyphInk = 0.7
+
+// Restyle returns a copy of t whose block sprites are recoloured to colors
+// and stamped with glyphs, both by sprite index. Sprites missing from colors
+// keep their colour and those missing from glyphs get no glyph. Recoloured
+// sprites keep the shading of the originals.
+func (t *Theme) Restyle(colors map[int]color.RGBA, glyphs map[int]Glyph) *Theme {
+	img := image.NewNRGBA(t.sheet.Bounds())
+	draw.Draw(img, img.Bounds(), t.sheet, t.sheet.Bounds().Min, draw.Src)
+	for i := 0; i < t.rows*t.cols; i++ {
+		r, c := i/t.cols, i%t.cols
+		tile := image.Rect(c*t.tile, r*t.tile, (c+1)*t.tile, (r+1)*t.tile).Add(img.Bounds().Min)
+		if col, ok := colors[i]; ok {
+			recolor(img, tile, col)
+		}
+		if g, ok := glyphs[i]; ok {
+			stamp(img, tile, g)
+		}
+	}
+	restyled := *t
+	restyled.setSheet(img, t.rows, t.cols, t.tile)
+	return &restyled
+}
+
+// BlockColor returns the average colour of the opaque pixels of block
+// sprite i, for drawing things in the colour of a block without its shading.
+func (t *Theme) BlockColor(i int) color.RGBA {
+	r, c := i/t.cols, i%t.cols
+	tile := image.Rect(c*t.tile, r*t.tile, (c+1)*t.tile, (r+1)*t.tile).Add(t.sheet.Bounds().Min)
+	var sum [3]float64
+	var n int
+	for y := tile.Min.Y; y < tile.Max.Y; y++ {
+		for x := tile.Min.X; x < tile.Max.X; x++ {
+			if px := color.NRGBAModel.Convert(t.sheet.At(x, y)).(color.NRGBA); px.A > 0 {
+				sum[0] += float64(px.R)
+				sum[1] += float64(px.G)
+				sum[2] += float64(px.B)
+				n++
+			}
+		}
+	}
+	if n == 0 {
+		return color.RGBA{}
+	}
+	return color.RGBA{
+		R: clampByte(sum[0] / float64(n)),
+		G: clampByte(sum[1] / float64(n)),
+		B: clampByte(sum[2] / float64(n)),
+		A: 0xff,
+	}
+}
+
+// recolor paints the sprite in tile of img with col, scaling col by how
+// much lighter or darker each pixel is than the sprite as a whole.
+func recolor(img *image.NRGBA, tile image.Rectangle, col color.RGBA) {
+	mean := meanLuminance(img, tile)
+	for y := tile.Min.Y; y < tile.Max.Y; y++ {
+		for x := tile.Min.X; x < tile.Max.X; x++ {
+			px := img.NRGBAAt(x, y)
+			f := 1.0
+			if mean > 0 {
+				f = luminance(px) / mean
+			}
+			img.SetNRGBA(x, y, color.NRGBA{
+				R: clampByte(float64(col.R) * f),
+				G: clampByte(float64(col.G) * f),
+				B: clampByte(float64(col.B) * f),
+				A: px.A,
+			})
+		}
+	}
+}
+
+// stamp draws g scaled up in the middle of the sprite in tile of img, in
+// black on light sprites and white on dark ones.
+func stamp(img *image.NRGBA, tile image.Rectangle, g Glyph) {
+	h := len(g)
+	w := 0
+	for _, row := range g {
+		if len(row) > w {
+			w = len(row)
+		}
+	}
+	if w == 0 {
+		return
+	}
+	size := h
+	if w > size {
+		size = w
+	}
+	px := tile.Dx() * 3 / 5 / size
+	if px < 1 {
+		px = 1
+	}
+	var ink float64
+	if meanLuminance(img, tile) < 140 {
+		ink = 0xff
+	}
+	origin := tile.Min.Add(image.Pt((tile.Dx()-w*px)/2, (tile.Dy()-h*px)/2))
+	for gy, row := range g {
+		for gx, set := range row {
+			if set != '#' {
+				continue
+			}
+			cell := image.Rect(gx*px, gy*px, (gx+1)*px, (gy+1)*px).Add(origin).Intersect(tile)
+			for y := cell.Min.Y; y < cell.Max.Y; y++ {
+				for x := cell.Min.X; x < cell.Max.X; x++ {
+					p := img.NRGBAAt(x, y)
+					p.R = clampByte(float64(p.R)*(1-glyphInk) + ink*glyphInk)
+					p.G = clampByte(float64(p.G)*(1-glyphInk) + ink*glyphInk)
+					p.B = clampByte(float64(p.B)*(1-glyphInk) + ink*glyphInk)
+					img.SetNRGBA(x, y, p)
+				}
+			}
+		}
+	}
+}
+
+// meanLuminance returns the average luminance of the pixels in tile of img
+// that are not transparent.
+func meanLuminance(img *image.NRGBA, tile image.Rectangle) float64 {
+	var sum float64
+	var n int
+	for y := tile.Min.Y; y < tile.Max.Y; y++ {
+		for x := tile.Min.X; x < tile.Max.X; x++ {
+			if px := img.NRGBAAt(x, y); px.A > 0 {
+				sum += luminance(px)
+				n++
+			}
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+// luminance returns how light px looks, from 0 to 255.
+func luminance(px color.NRGBA) float64 {
+	return 0.299*float64(px.R) + 0.587*float64(px.G) + 0.114*float64(px.B)
+}
+
+func clampByte(v float64) uint8 {
+	switch {
+	case v < 0:
+		return 0
+	case v > 0xff:
+		return 0xff
+	}
+	return uint8(v + 0.5)
+}
@@ -0,0 +1,298 @@
+package spritesheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/faiface/pixel"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+)
+
+// BlockSprites is how many block sprites a theme's sprite sheet must hold,
+// one for every colour of block.
+const BlockSprites = 16
+
+// ThemeFile is the name of the file describing a theme, at the top of the
+// theme's directory or zip file.
+const ThemeFile = "theme.json"
+
+// Theme is the art the game is drawn with.
+type Theme struct {
+	Sheet      pixel.Picture           // Sprite sheet of the blocks
+	Frames     []pixel.Rect            // Part of Sheet holding each block sprite, by index
+	Blocks     func(int) pixel.Picture // Block sprites as pictures of their own, by index
+	Background pixel.Picture           // Drawn behind everything, centred
+	Panel      color.RGBA              // Boxes behind the playing field, score and next piece
+	Text       color.RGBA
+	Face       font.Face
+
+	sheet            image.Image // Sheet as read, for Restyle
+	rows, cols, tile int
+}
+
+// themeManifest is the contents of ThemeFile. Paths are relative to the
+// theme.
+type themeManifest struct {
+	Blocks     string  `json:"blocks"` // Sprite sheet of the blocks
+	Rows       int     `json:"rows"`   // Rows of sprites in the sheet
+	Cols       int     `json:"cols"`   // Columns of sprites in the sheet
+	Tile       int     `json:"tile"`   // Side of a sprite in pixels, or 0 to fit the sheet
+	Background string  `json:"background"`
+	Panel      string  `json:"panel"`    // Colour as #rrggbb or #rrggbbaa
+	Text       string  `json:"text"`     // Colour as #rrggbb or #rrggbbaa
+	Font       string  `json:"font"`     // TrueType or OpenType font, or empty for the built in one
+	FontSize   float64 `json:"fontSize"` // Size of the font in points
+}
+
+// defaultManifest describes the theme built into the binary.
+var defaultManifest = themeManifest{
+	Blocks:     "blocks.png",
+	Rows:       2,
+	Cols:       8,
+	Background: "bg_whitecanvas.png",
+	Panel:      "#000000a0",
+	Text:       "#ffffff",
+}
+
+// DefaultTheme returns the theme built into the binary.
+func DefaultTheme() (*Theme, error) {
+	return loadTheme(pictrues, defaultManifest)
+}
+
+// LoadTheme reads the theme in the directory or zip file at path. Options
+// left out of its ThemeFile are taken from the default theme, except for the
+// sprite sheet and background which every theme has to give.
+func LoadTheme(path string) (*Theme, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	var fsys fs.FS
+	if info.IsDir() {
+		fsys = os.DirFS(path)
+	} else {
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		fsys = zr
+	}
+
+	data, err := fs.ReadFile(fsys, ThemeFile)
+	if err != nil {
+		return nil, fmt.Errorf("theme %s: %v", path, err)
+	}
+	m := defaultManifest
+	m.Blocks, m.Background = "", ""
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("theme %s: %s: %v", path, ThemeFile, err)
+	}
+	if m.Blocks == "" {
+		return nil, fmt.Errorf("theme %s: no block sprite sheet", path)
+	}
+	if m.Background == "" {
+		return nil, fmt.Errorf("theme %s: no background", path)
+	}
+	t, err := loadTheme(fsys, m)
+	if err != nil {
+		return nil, fmt.Errorf("theme %s: %v", path, err)
+	}
+	return t, nil
+}
+
+// loadTheme reads the files named by m from fsys. Everything is read before
+// it returns, so fsys can be closed afterwards.
+func loadTheme(fsys fs.FS, m themeManifest) (*Theme, error) {
+	var t Theme
+	var err error
+	if t.Panel, err = ParseColor(m.Panel); err != nil {
+		return nil, fmt.Errorf("panel: %v", err)
+	}
+	if t.Text, err = ParseColor(m.Text); err != nil {
+		return nil, fmt.Errorf("text: %v", err)
+	}
+
+	sheet, err := decodeImage(fsys, m.Blocks)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSheet(sheet.Bounds(), m); err != nil {
+		return nil, fmt.Errorf("%s: %v", m.Blocks, err)
+	}
+	tile := m.Tile
+	if tile == 0 {
+		tile = sheet.Bounds().Dx() / m.Cols
+	}
+	t.setSheet(sheet, m.Rows, m.Cols, tile)
+
+	bg, err := decodeImage(fsys, m.Background)
+	if err != nil {
+		return nil, err
+	}
+	t.Background = pixel.PictureDataFromImage(bg)
+
+	t.Face = basicfont.Face7x13
+	if m.Font != "" {
+		if t.Face, err = loadFace(fsys, m.Font, m.FontSize); err != nil {
+			return nil, err
+		}
+	}
+	return &t, nil
+}
+
+// checkSheet returns an error unless a sprite sheet with the given bounds
+// fits the layout in m and holds a sprite for every block. Without a tile
+// size the sprites must be square once the sheet is split evenly.
+func checkSheet(bounds image.Rectangle, m themeManifest) error {
+	if m.Rows < 1 || m.Cols < 1 || m.Tile < 0 {
+		return fmt.Errorf("invalid layout of %d rows and %d columns of %d pixel sprites", m.Rows, m.Cols, m.Tile)
+	}
+	if m.Rows*m.Cols < BlockSprites {
+		return fmt.Errorf("%d sprites, want at least %d", m.Rows*m.Cols, BlockSprites)
+	}
+	w, h := bounds.Dx(), bounds.Dy()
+	if m.Tile == 0 {
+		if w/m.Cols != h/m.Rows || w < m.Cols {
+			return fmt.Errorf("sheet is %dx%d, which does not split into %d rows and %d columns of square sprites", w, h, m.Rows, m.Cols)
+		}
+		return nil
+	}
+	if w < m.Cols*m.Tile || h < m.Rows*m.Tile {
+		return fmt.Errorf("sheet is %dx%d, want at least %dx%d for %d rows and %d columns of %d pixel sprites",
+			w, h, m.Cols*m.Tile, m.Rows*m.Tile, m.Rows, m.Cols, m.Tile)
+	}
+	return nil
+}
+
+// setSheet makes img, with the given layout, the sprite sheet of t.
+func (t *Theme) setSheet(img image.Image, rows, cols, tile int) {
+	t.sheet, t.rows, t.cols, t.tile = img, rows, cols, tile
+	t.Blocks = blockSheet(img, rows, cols, tile)
+	t.Sheet = pixel.PictureDataFromImage(img)
+	t.Frames = sheetFrames(t.Sheet.Bounds(), rows, cols, tile)
+}
+
+// sheetFrames returns the part of a sheet with the given bounds holding each
+// sprite, counting across each row from the top left as blockSheet does.
+// Pictures have their origin at the bottom left, unlike images.
+func sheetFrames(bounds pixel.Rect, rows, cols, tile int) []pixel.Rect {
+	size := float64(tile)
+	frames := make([]pixel.Rect, 0, rows*cols)
+	for r := 0; r < rows; r++ {
+		top := bounds.Max.Y - float64(r)*size
+		for c := 0; c < cols; c++ {
+			left := bounds.Min.X + float64(c)*size
+			frames = append(frames, pixel.R(left, top-size, left+size, top))
+		}
+	}
+	return frames
+}
+
+func decodeImage(fsys fs.FS, name string) (image.Image, error) {
+	f, err := fsys.Open(filepath.ToSlash(name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return img, nil
+}
+
+// loadFace reads the font file name from fsys at the given size in points.
+func loadFace(fsys fs.FS, name string, size float64) (font.Face, error) {
+	data, err := fs.ReadFile(fsys, filepath.ToSlash(name))
+	if err != nil {
+		return nil, err
+	}
+	face, err := parseFace(data, size)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return face, nil
+}
+
+// LoadFont reads the TrueType or OpenType font at path, at the given size in
+// points. For a collection of fonts, such as a .ttc file, the first font is
+// used.
+func LoadFont(path string, size float64) (font.Face, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	face, err := parseFace(data, size)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return face, nil
+}
+
+// parseFace reads a font, or the first font of a collection, from data.
+func parseFace(data []byte, size float64) (font.Face, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid font size %v", size)
+	}
+	var f *sfnt.Font
+	var err error
+	if bytes.HasPrefix(data, []byte("ttcf")) {
+		var c *sfnt.Collection
+		if c, err = sfnt.ParseCollection(data); err == nil {
+			f, err = c.Font(0)
+		}
+	} else {
+		f, err = sfnt.Parse(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+}
+
+// ParseColor reads a colour written as #rrggbb or #rrggbbaa.
+func ParseColor(s string) (color.RGBA, error) {
+	c := color.RGBA{A: 0xff}
+	hex := strings.TrimPrefix(s, "#")
+	var err error
+	switch len(hex) {
+	case 6:
+		_, err = fmt.Sscanf(hex, "%02x%02x%02x", &c.R, &c.G, &c.B)
+	case 8:
+		_, err = fmt.Sscanf(hex, "%02x%02x%02x%02x", &c.R, &c.G, &c.B, &c.A)
+	default:
+		err = errors.New("wrong length")
+	}
+	if err != nil || !strings.HasPrefix(s, "#") {
+		return c, fmt.Errorf("invalid colour %q: want #rrggbb or #rrggbbaa", s)
+	}
+	// Pictures are drawn with premultiplied alpha
+	c.R = uint8(uint16(c.R) * uint16(c.A) / 0xff)
+	c.G = uint8(uint16(c.G) * uint16(c.A) / 0xff)
+	c.B = uint8(uint16(c.B) * uint16(c.A) / 0xff)
+	return c, nil
+}
+
+// SolidPicture returns a w by h picture filled with c.
+func SolidPicture(w, h int, c color.RGBA) pixel.Picture {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return pixel.PictureDataFromImage(img)
+}
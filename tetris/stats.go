@@ -0,0 +1,178 @@
+package tetris
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// clearNames names what a piece locking can clear, indexed by the number of
+// rows cleared.
+var clearNames = [...]string{"none", "single", "double", "triple", "tetris"}
+
+// Stats are measurements of how a game was played.
+type Stats struct {
+	Seconds    float64        `json:"seconds"`
+	Score      int            `json:"score"`
+	Pieces     int            `json:"pieces"`
+	PieceCount map[string]int `json:"pieceCount"` // Pieces placed of each kind
+	Inputs     int            `json:"inputs"`     // Moves, rotations and hard drops
+	Lines      int            `json:"lines"`
+	Clears     map[string]int `json:"clears"` // Pieces locked by what they cleared, eg "double" or "tspinSingle"
+	Attack     int            `json:"attack"` // Garbage rows earned, before cancelling
+	Combo      int            `json:"-"`      // Pieces in a row that cleared rows
+	MaxCombo   int            `json:"maxCombo"`
+	Holes      int            `json:"-"` // Holes on the board after the last lock
+	HolesMade  int            `json:"holesCreated"`
+	LevelTimes []float64      `json:"levelSeconds"` // Time spent on each level finished
+
+	PPS float64 `json:"pps"` // Pieces per second
+	KPP float64 `json:"kpp"` // Inputs per piece
+	APM float64 `json:"apm"` // Attack per minute
+}
+
+func newStats() *Stats {
+	return &Stats{PieceCount: make(map[string]int), Clears: make(map[string]int), LevelTimes: []float64{}}
+}
+
+// input records an action made by the player.
+func (s *Stats) input(a Action) {
+	if a != Gravity {
+		s.Inputs++
+	}
+}
+
+// lock records a piece that just locked on b.
+func (s *Stats) lock(ev PieceLocked, b *Board) {
+	s.Pieces++
+	s.PieceCount[b.set.def(ev.Piece).Name]++
+	s.Lines += ev.Cleared
+
+	cleared := ev.Cleared
+	if cleared >= len(clearNames) {
+		cleared = len(clearNames) - 1
+	}
+	name := clearNames[cleared]
+	if ev.TSpin {
+		name = "tspin"
+		if cleared > 0 {
+			name += strings.ToUpper(clearNames[cleared][:1]) + clearNames[cleared][1:]
+		}
+	}
+	if name != "none" {
+		s.Clears[name]++
+	}
+	s.Attack += attackTable[cleared]
+
+	if ev.Cleared > 0 {
+		s.Combo++
+		if s.Combo > s.MaxCombo {
+			s.MaxCombo = s.Combo
+		}
+	} else {
+		s.Combo = 0
+	}
+
+	holes := countHoles(&b.board)
+	if holes > s.Holes {
+		s.HolesMade += holes - s.Holes
+	}
+	s.Holes = holes
+	s.update()
+}
+
+// tick adds dt seconds of play.
+func (s *Stats) tick(dt float64) {
+	s.Seconds += dt
+	s.update()
+}
+
+// levelUp records the time the level just finished took.
+func (s *Stats) levelUp() {
+	total := 0.0
+	for _, t := range s.LevelTimes {
+		total += t
+	}
+	s.LevelTimes = append(s.LevelTimes, s.Seconds-total)
+}
+
+// update works out the rates from the totals.
+func (s *Stats) update() {
+	if s.Seconds > 0 {
+		s.PPS = float64(s.Pieces) / s.Seconds
+		s.APM = float64(s.Attack) / s.Seconds * 60
+	}
+	if s.Pieces > 0 {
+		s.KPP = float64(s.Inputs) / float64(s.Pieces)
+	}
+}
+
+// summary lays the stats out for the side panel, in language l.
+func (s *Stats) summary(l Language) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\n\t"+l.tr("Time %s")+"\n", time.Duration(s.Seconds*float64(time.Second)).Round(time.Second))
+	fmt.Fprintf(&sb, "\t"+l.tr("Pieces %d  PPS %.2f")+"\n", s.Pieces, s.PPS)
+	fmt.Fprintf(&sb, "\t"+l.tr("KPP %.2f  APM %.1f")+"\n", s.KPP, s.APM)
+	fmt.Fprintf(&sb, "\t"+l.tr("Lines %d  Max combo %d")+"\n", s.Lines, s.MaxCombo)
+	fmt.Fprintf(&sb, "\t"+l.tr("Holes created %d")+"\n", s.HolesMade)
+	for _, name := range []string{"single", "double", "triple", "tetris", "tspin", "tspinSingle", "tspinDouble", "tspinTriple"} {
+		if n := s.Clears[name]; n > 0 {
+			fmt.Fprintf(&sb, "\t%s %d\n", l.tr(name), n)
+		}
+	}
+	names := make([]string, 0, len(s.PieceCount))
+	for p := range s.PieceCount {
+		names = append(names, p)
+	}
+	sort.Strings(names)
+	for i, p := range names {
+		if i%4 == 0 {
+			sb.WriteString("\n\t")
+		}
+		fmt.Fprintf(&sb, "%s %d  ", p, s.PieceCount[p])
+	}
+	return sb.String()
+}
+
+// Save writes the stats as JSON to path, creating its directory if needed.
+func (s *Stats) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// defaultStatsPath returns where the stats of a game ending at t are saved
+// when no other path is given.
+func defaultStatsPath(t time.Time) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tetris-go", "stats", t.Format("20060102-150405")+".json"), nil
+}
+
+// countHoles counts the empty cells that have a filled cell somewhere above
+// them in the same column.
+func countHoles(cells *[BoardRows][BoardCols]Block) int {
+	holes := 0
+	for c := 0; c < BoardCols; c++ {
+		covered := false
+		for r := BoardRows - 1; r >= 0; r-- {
+			if cells[r][c] != Empty {
+				covered = true
+			} else if covered {
+				holes++
+			}
+		}
+	}
+	return holes
+}
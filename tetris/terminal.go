@@ -0,0 +1,444 @@
+package tetris
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// terminalFrameTime is how often the terminal game updates.
+const terminalFrameTime = time.Second / 60
+
+// blockColors are the ANSI 256 colour codes the terminal draws each block
+// type in. Special blocks share the colour of their plain kind.
+var blockColors = [...]int{
+	Goluboy: 51,
+	Siniy:   27,
+	Pink:    213,
+	Purple:  129,
+	Red:     196,
+	Yellow:  226,
+	Green:   46,
+	Gray:    244,
+}
+
+// termKey is a key read from the terminal.
+type termKey int
+
+// Keys the terminal game responds to
+const (
+	termNone termKey = iota
+	termLeft
+	termRight
+	termUp
+	termRotateCCW
+	termRotate180
+	termDown
+	termSpace
+	termPause
+	termQuit
+)
+
+// terminalKeys are the keys of the terminal game, with the names and actions
+// they are listed under in the controls help. Letters are read in either
+// case.
+var terminalKeys = []struct {
+	key    termKey
+	char   byte // The byte the key arrives as, or 0 for an arrow key
+	name   string
+	action string
+}{
+	{termLeft, 0, "Left", "Move block"},
+	{termRight, 0, "Right", "Move block"},
+	{termUp, 0, "Up", "Rotate block"},
+	{termRotateCCW, 'z', "Z", "Rotate left"},
+	{termRotate180, 'a', "A", "Rotate 180"},
+	{termDown, 0, "Down", "Fast fall"},
+	{termSpace, ' ', "Space", "Instant drop"},
+	{termPause, 'p', "P", "Pause"},
+	{termQuit, 'q', "Q", "Quit"},
+	{termQuit, 3, "Ctrl-C", "Quit"}, // Raw mode passes Ctrl-C through as 3
+}
+
+// terminalBindings lists the keys of the terminal game for the controls
+// help, with keys making the same action on one line.
+func terminalBindings() []binding {
+	var bs []binding
+	for _, k := range terminalKeys {
+		if n := len(bs); n > 0 && bs[n-1].action == k.action {
+			bs[n-1].buttons = append(bs[n-1].buttons, k.name)
+		} else {
+			bs = append(bs, binding{[]string{k.name}, k.action})
+		}
+	}
+	return bs
+}
+
+// charKey returns the key read as the byte c, if any.
+func charKey(c byte) termKey {
+	if c >= 'A' && c <= 'Z' {
+		c += 'a' - 'A'
+	}
+	for _, k := range terminalKeys {
+		if k.char != 0 && k.char == c {
+			return k.key
+		}
+	}
+	return termNone
+}
+
+// terminalGame plays a single player game in a terminal, drawing the board
+// with ANSI colours and reading keys in raw mode. It suits playing over SSH.
+type terminalGame struct {
+	config Config
+	board  *Board
+	player *player
+	bot    Bot
+
+	keys     chan termKey
+	out      *bufio.Writer
+	oldState *term.State
+	last     string // The frame last written, so unchanged frames are skipped
+
+	isPaused bool
+	quit     bool
+}
+
+// NewTerminalGame creates a game played by cfg in the terminal instead of a
+// window. Returns an error if cfg is not valid.
+func NewTerminalGame(cfg Config) (*terminalGame, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+	return &terminalGame{config: cfg}, nil
+}
+
+// SetBot puts bot in control of the game instead of the keyboard.
+func (g *terminalGame) SetBot(bot Bot) {
+	g.bot = bot
+}
+
+func (g *terminalGame) Initialize() {
+	g.board = newRuledBoard(g.config.Seed, g.config.Ruleset, g.config.Preview, g.config.pieceSet())
+	g.board.AddPiece()
+	g.player = newPlayer(g.board, defaultKeys)
+	g.player.bot = g.bot
+	g.player.baseSpeed = levelSpeed(g.config.Level)
+	g.player.gravitySpeed = g.player.baseSpeed
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		panic("tetris: the terminal game needs stdin to be a terminal")
+	}
+	var err error
+	g.oldState, err = term.MakeRaw(fd)
+	if err != nil {
+		panic(err)
+	}
+	g.out = bufio.NewWriter(os.Stdout)
+	// Switch to the alternate screen and hide the cursor
+	g.out.WriteString("\x1b[?1049h\x1b[?25l\x1b[2J")
+	g.out.Flush()
+
+	g.keys = make(chan termKey, 16)
+	go readKeys(os.Stdin, g.keys)
+}
+
+func (g *terminalGame) Run() {
+	defer g.close()
+	ticker := time.NewTicker(terminalFrameTime)
+	defer ticker.Stop()
+	last := time.Now()
+	for !g.quit && !g.finished() {
+		<-ticker.C
+		dt := time.Since(last).Seconds()
+		last = time.Now()
+
+		g.processKeys()
+		if !g.isPaused {
+			g.player.advance(dt)
+		}
+		g.draw()
+	}
+	if g.finished() {
+		g.draw()
+		// Leave the final board up for a moment
+		time.Sleep(2 * time.Second)
+	}
+}
+
+// finished reports whether the game has ended, by topping out or by
+// clearing the rows of a sprint.
+func (g *terminalGame) finished() bool {
+	return g.board.GameOver() || g.config.Mode == Sprint && g.board.lines >= sprintLines
+}
+
+// close puts the terminal back the way it was found.
+func (g *terminalGame) close() {
+	g.out.WriteString("\x1b[0m\x1b[?25h\x1b[?1049l")
+	g.out.Flush()
+	term.Restore(int(os.Stdin.Fd()), g.oldState)
+	fmt.Printf("Score %d, lines %d\n", g.board.score, g.board.lines)
+}
+
+// processKeys applies the keys pressed since the last frame. The terminal
+// repeats held keys itself, so every key makes one move.
+func (g *terminalGame) processKeys() {
+	for {
+		select {
+		case k, ok := <-g.keys:
+			if !ok {
+				// Stdin has closed, so the game cannot be played any more
+				g.quit = true
+				return
+			}
+			g.handleKey(k)
+		default:
+			return
+		}
+	}
+}
+
+func (g *terminalGame) handleKey(k termKey) {
+	switch k {
+	case termQuit:
+		g.quit = true
+		return
+	case termPause:
+		g.isPaused = !g.isPaused
+		return
+	}
+	if g.isPaused || g.bot != nil {
+		return
+	}
+	p := g.player
+	switch k {
+	case termLeft:
+		p.apply(MoveLeft)
+	case termRight:
+		p.apply(MoveRight)
+	case termUp:
+		p.rotate(Rotate)
+	case termRotateCCW:
+		p.rotate(RotateCCW)
+	case termRotate180:
+		p.rotate(Rotate180)
+	case termDown:
+		if !p.apply(Gravity) {
+			p.gravityTimer = 0
+		}
+	case termSpace:
+		p.apply(HardDrop)
+	}
+}
+
+// readKeys reads key presses from r and sends them to keys until r fails.
+func readKeys(r *os.File, keys chan<- termKey) {
+	var p keyParser
+	buf := make([]byte, 32)
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			close(keys)
+			return
+		}
+		for _, k := range p.parseKeys(buf[:n]) {
+			keys <- k
+		}
+	}
+}
+
+// keyParser turns bytes read from a terminal in raw mode into keys. Arrow
+// keys arrive as the escape sequences ESC [ A to ESC [ D, which can be split
+// across reads over a slow connection, so an unfinished sequence is kept
+// until the rest of it arrives. Escape on its own does nothing.
+type keyParser struct {
+	pending []byte // The start of an escape sequence
+}
+
+// parseKeys returns the keys completed by data.
+func (p *keyParser) parseKeys(data []byte) []termKey {
+	data = append(p.pending, data...)
+	p.pending = nil
+	var keys []termKey
+	for i := 0; i < len(data); i++ {
+		var k termKey
+		switch data[i] {
+		case 0x1b:
+			n, key := escapeSequence(data[i:])
+			if n == 0 {
+				p.pending = append([]byte{}, data[i:]...)
+				return keys
+			}
+			i += n - 1
+			k = key
+		default:
+			k = charKey(data[i])
+		}
+		if k != termNone {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// escapeSequence reads the escape sequence at the start of data, returning
+// its length and the arrow key it stands for, if any. Returns a length of 0
+// if data ends before the sequence does. An escape not starting a sequence
+// is read on its own.
+func escapeSequence(data []byte) (int, termKey) {
+	if len(data) < 2 {
+		return 0, termNone
+	}
+	switch data[1] {
+	case 'O':
+		if len(data) < 3 {
+			return 0, termNone
+		}
+		return 3, arrowKey(data[2])
+	case '[':
+		// Parameters come before the final byte, as in ESC [ 1 ; 5 C
+		for n := 2; n < len(data); n++ {
+			if data[n] >= 0x40 && data[n] <= 0x7e {
+				return n + 1, arrowKey(data[n])
+			}
+		}
+		return 0, termNone
+	}
+	return 1, termNone
+}
+
+// arrowKey returns the key of the final byte of an arrow key sequence.
+func arrowKey(b byte) termKey {
+	switch b {
+	case 'A':
+		return termUp
+	case 'B':
+		return termDown
+	case 'C':
+		return termRight
+	case 'D':
+		return termLeft
+	}
+	return termNone
+}
+
+// draw writes the board and side panel to the terminal, if they changed
+// since the last frame.
+func (g *terminalGame) draw() {
+	frame := g.board.terminalFrame(g.panel())
+	if frame == g.last {
+		return
+	}
+	g.last = frame
+	g.out.WriteString("\x1b[H")
+	g.out.WriteString(frame)
+	g.out.Flush()
+}
+
+// panel returns the lines shown beside the board.
+func (g *terminalGame) panel() []string {
+	b := g.board
+	lines := []string{
+		fmt.Sprintf("Score  %d", b.score),
+		fmt.Sprintf("Lines  %d", b.lines),
+		fmt.Sprintf("Pieces %d", b.pieces),
+		"",
+		"Next",
+	}
+	lines = append(lines, b.pieceRows(b.nextPiece)...)
+	lines = append(lines, "")
+	switch {
+	case b.GameOver():
+		lines = append(lines, "Game Over")
+	case g.isPaused:
+		lines = append(lines, "Game Pause")
+	case g.bot != nil:
+		lines = append(lines, "Bot playing")
+	default:
+		lines = append(lines, "")
+	}
+	lines = append(lines, "")
+	for _, line := range strings.Split(strings.TrimSpace(helpText(terminalBindings(), English)), "\n") {
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	return lines
+}
+
+// terminalFrame draws the visible rows of the board with ANSI colours, with
+// the ghost of the active piece and panel written to the right.
+func (b *Board) terminalFrame(panel []string) string {
+	ghost := b.ghostShape()
+	var sb strings.Builder
+	line := 0
+	writeLine := func(s string) {
+		sb.WriteString(s)
+		sb.WriteString("  ")
+		if line < len(panel) {
+			sb.WriteString(panel[line])
+		}
+		// Clear whatever was left on the line by the last frame
+		sb.WriteString("\x1b[K\r\n")
+		line++
+	}
+
+	writeLine("┌" + strings.Repeat("─", BoardCols*2) + "┐")
+	for r := BoardRows - 3; r >= 0; r-- {
+		var row strings.Builder
+		row.WriteString("│")
+		for c := 0; c < BoardCols; c++ {
+			val := b.board[r][c]
+			switch {
+			case val != Empty:
+				row.WriteString(colorCell(val, "██"))
+			case shapeContains(b.activeShape, r, c):
+				row.WriteString(colorCell(b.pieceBlock(b.currentPiece), "██"))
+			case shapeContains(ghost, r, c):
+				row.WriteString(colorCell(b.pieceBlock(b.currentPiece), "░░"))
+			default:
+				row.WriteString(" .")
+			}
+		}
+		row.WriteString("│")
+		writeLine(row.String())
+	}
+	writeLine("└" + strings.Repeat("─", BoardCols*2) + "┘")
+	return sb.String()
+}
+
+// colorCell wraps s in the ANSI codes that draw it in the colour of block t.
+func colorCell(t Block, s string) string {
+	if t >= GoluboySpecial {
+		t -= GoluboySpecial - Goluboy
+	}
+	return fmt.Sprintf("\x1b[38;5;%dm%s\x1b[0m", blockColors[t], s)
+}
+
+// pieceRows draws piece p as it spawns, top row first, for the side panel.
+func (b *Board) pieceRows(p Piece) []string {
+	s := b.set.shape(p)
+	minRow, maxRow, minCol, maxCol := shapeBounds(s)
+	rows := make([]string, 0, 2)
+	for r := maxRow; r >= minRow; r-- {
+		var row strings.Builder
+		for c := minCol; c <= maxCol; c++ {
+			if shapeContains(s, r, c) {
+				row.WriteString(colorCell(b.pieceBlock(p), "██"))
+			} else {
+				row.WriteString("  ")
+			}
+		}
+		rows = append(rows, row.String())
+	}
+	for len(rows) < 2 {
+		rows = append(rows, "")
+	}
+	return rows
+}
@@ -0,0 +1,52 @@
+package tetris
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		reads []string
+		want  []termKey
+	}{
+		{"letters", []string{" zap"}, []termKey{termSpace, termRotateCCW, termRotate180, termPause}},
+		{"arrows", []string{"\x1b[A\x1b[B\x1b[C\x1b[D"}, []termKey{termUp, termDown, termRight, termLeft}},
+		{"application arrows", []string{"\x1bOA\x1bOD"}, []termKey{termUp, termLeft}},
+		{"quit", []string{"q", "Q", "\x03"}, []termKey{termQuit, termQuit, termQuit}},
+		{"split after escape", []string{"\x1b", "[A"}, []termKey{termUp}},
+		{"split after bracket", []string{"\x1b[", "A"}, []termKey{termUp}},
+		{"split in three", []string{"z\x1b", "[", "Cz"}, []termKey{termRotateCCW, termRight, termRotateCCW}},
+		{"parameters", []string{"\x1b[1;5", "C"}, []termKey{termRight}},
+		{"lone escape", []string{"\x1b"}, nil},
+		{"escape then key", []string{"\x1b", " "}, []termKey{termSpace}},
+		{"other sequence", []string{"\x1b[2~a"}, []termKey{termRotate180}},
+	}
+	for _, tt := range tests {
+		var p keyParser
+		var got []termKey
+		for _, r := range tt.reads {
+			got = append(got, p.parseKeys([]byte(r))...)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestTerminalKeysParse checks that every key listed in the help is read as
+// the key it is listed as.
+func TestTerminalKeysParse(t *testing.T) {
+	arrows := map[termKey]string{termUp: "\x1b[A", termDown: "\x1b[B", termRight: "\x1b[C", termLeft: "\x1b[D"}
+	for _, k := range terminalKeys {
+		in := arrows[k.key]
+		if k.char != 0 {
+			in = string(k.char)
+		}
+		var p keyParser
+		if got := p.parseKeys([]byte(in)); !reflect.DeepEqual(got, []termKey{k.key}) {
+			t.Errorf("%s (%q) read as %v, want %v", k.name, in, got, k.key)
+		}
+	}
+}
@@ -1,63 +1,163 @@
+//go:build !js
+
 package tetris
 
 import (
-	"math"
+	"fmt"
+	"log"
+	"strings"
 	"time"
 
-	"github.com/faiface/pixel"
 	"github.com/faiface/pixel/pixelgl"
+	"github.com/yankooo/tetris-go/tetris/netplay"
 	"golang.org/x/image/colornames"
 )
 
-type tetrisGame struct {
-	win   *pixelgl.Window
-	board *Board
+// gameKey is a key of a single player game that changes how it is played
+// or shown.
+type gameKey struct {
+	key    pixelgl.Button
+	action string // What the key does, in English, for the controls help
+	do     func(*tetrisGame)
+}
+
+// gameKeys are the keys a single player game has on top of the player's.
+var gameKeys = []gameKey{
+	{pixelgl.KeyH, "Toggle hint", (*tetrisGame).toggleHint},
+	{pixelgl.KeyTab, "Show stats", (*tetrisGame).toggleStats},
+	{pixelgl.KeyF, "Finesse training", (*tetrisGame).cycleFinesse},
+	{pixelgl.KeyV, "Colour palette", (*tetrisGame).cyclePalette},
+	{pixelgl.KeyG, "Piece letters", (*tetrisGame).toggleGlyphs},
+	{pixelgl.KeyL, "Language", (*tetrisGame).cycleLanguage},
+	{pixelgl.KeyO, "Ghost piece", (*tetrisGame).cycleGhost},
+	{pixelgl.KeyF12, "Screenshot", (*tetrisGame).screenshot},
+	{pixelgl.KeyF9, "Save GIF", (*tetrisGame).saveClip},
+}
+
+// pauseBinding is the control pausing every game in a window.
+var pauseBinding = binding{[]string{buttonName(pixelgl.MouseButtonLeft)}, "Pause"}
 
-	gravityTimer   float64
-	baseSpeed      float64
-	gravitySpeed   float64
-	levelUpTimer   float64
-	leftRightDelay float64
-	moveCounter    int
+// singleBindings lists the keyboard controls of a single player game.
+func singleBindings() []binding {
+	bs := defaultKeys.bindings()
+	for _, k := range gameKeys {
+		bs = append(bs, binding{[]string{buttonName(k.key)}, k.action})
+	}
+	return append(bs, binding{[]string{buttonName(fullscreenKey)}, "Fullscreen"}, pauseBinding)
+}
+
+type tetrisGame struct {
+	win    *pixelgl.Window
+	board  *Board
+	player *player
 
 	isPaused bool
+
+	bot          Bot
+	spectateAddr string
+	stream       *netplay.Stream
+
+	config   Config
+	settings Settings
+	assisted bool // Whether a hint has been shown during the game
+	hint     hint
+
+	saved *savedGame // A game left unfinished last time, offered in the menu
+
+	recordPath string  // Where the replay of the game is saved when it ends
+	recording  *Replay // The replay being recorded for recordPath and GIFs, if any
+	replay     *Replay // The replay being played back instead of the keyboard
+	replayNext int     // Index of the next action of replay
+}
+
+// NewGame creates a single player game played by cfg. Returns an error if
+// cfg is not valid.
+func NewGame(cfg Config) (*tetrisGame, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+	g := &tetrisGame{config: cfg}
+	return g, nil
 }
 
-func NewGame() *tetrisGame {
-	g := &tetrisGame{}
-	return g
+// NewReplayGame creates a game that plays back r in the window.
+func NewReplayGame(r *Replay) (*tetrisGame, error) {
+	g, err := NewGame(r.Config)
+	if err != nil {
+		return nil, err
+	}
+	g.replay = r
+	return g, nil
 }
 
-func (g *tetrisGame) Initialize() {
-	g.baseSpeed = 0.8
-	g.gravitySpeed = g.baseSpeed
-	g.levelUpTimer = levelLength // Assuming levelLength is initialized elsewhere
-	g.initWindows()
+// ServeSpectators makes the game stream its board to spectators connecting
+// to addr once it is initialized.
+func (g *tetrisGame) ServeSpectators(addr string) {
+	g.spectateAddr = addr
+}
 
-	g.board = NewBoard()
-	g.board.initResource()
-	g.board.AddPiece()
+// RecordTo makes the game save a replay of itself to path when it ends.
+func (g *tetrisGame) RecordTo(path string) {
+	g.recordPath = path
 }
 
-func (g *tetrisGame) initWindows() {
+// SetBot puts bot in control of the game instead of the keyboard.
+func (g *tetrisGame) SetBot(bot Bot) {
+	g.bot = bot
+}
+
+func (g *tetrisGame) Initialize() {
 	var err error
-	windowWidth := 765.0
-	windowHeight := 450.0
-	cfg := pixelgl.WindowConfig{
-		Title:  "俄罗斯方块",
-		Bounds: pixel.R(0, 0, windowWidth, windowHeight),
-		VSync:  true,
-	}
-	g.win, err = pixelgl.NewWindow(cfg)
+	g.settings, err = LoadSettings()
 	if err != nil {
-		panic(err)
+		log.Printf("using default settings: %v", err)
+	}
+	g.win = newWindow(windowWidth, g.settings.Language)
+
+	g.board = newRuledBoard(g.config.Seed, g.config.Ruleset, g.config.Preview, g.config.pieceSet())
+	g.board.useSettings(g.settings)
+	g.board.AddPiece()
+	g.player = newPlayer(g.board, defaultKeys)
+	g.player.bot = g.bot
+	g.player.baseSpeed = levelSpeed(g.config.Level)
+	g.player.gravitySpeed = g.player.baseSpeed
+
+	// Games are always recorded so that GIFs can be made of them
+	if g.replay == nil {
+		g.recording = &Replay{Version: replayVersion, Config: g.config}
+		g.player.onAction = g.record
+	}
+
+	// Bots and replays do not take over a player's unfinished game
+	if g.bot == nil && g.replay == nil {
+		saved, ok, err := loadSave()
+		if err != nil {
+			log.Printf("ignoring saved game: %v", err)
+		}
+		if ok {
+			g.saved = &saved
+		}
+	}
+
+	if g.spectateAddr != "" {
+		g.stream, err = netplay.ListenStream(g.spectateAddr)
+		if err != nil {
+			panic(err)
+		}
 	}
 }
 
 func (g *tetrisGame) Run() {
+	if g.stream != nil {
+		defer g.stream.Close()
+	}
 	last := time.Now()
-	for !g.win.Closed() && !g.board.GameOver() {
-		if g.win.JustPressed(pixelgl.MouseButtonLeft) {
+	for !g.win.Closed() && !g.finished() {
+		g.win.SetMatrix(fitWindow(g.win, windowWidth))
+		if g.win.JustPressed(pixelgl.MouseButtonLeft) || padJustPressed(g.win, defaultPad.pause) {
 			last = g.togglePause(last)
 		}
 
@@ -67,99 +167,277 @@ func (g *tetrisGame) Run() {
 			continue
 		}
 
-		dt := time.Since(last).Seconds()
-		last = time.Now()
-		g.gravityTimer += dt
-		g.levelUpTimer -= dt
-
-		if g.gravityTimer > g.gravitySpeed {
-			g.gravityTimer -= g.gravitySpeed
-			didCollide := g.board.applyGravity()
-			if !didCollide {
-				if g.board.isTouchingFloor() {
-					g.gravityTimer -= g.gravitySpeed
-				}
-			} else {
-				g.board.AddScore(10)
-			}
-		}
-		if g.leftRightDelay > 0.0 {
-			g.leftRightDelay = math.Max(g.leftRightDelay-dt, 0.0)
+		if g.saved != nil {
+			g.chooseContinue()
+			last = time.Now()
+			continue
 		}
 
-		if g.levelUpTimer <= 0 {
-			if g.baseSpeed > 0.2 {
-				g.baseSpeed = math.Max(g.baseSpeed-speedUpRate, 0.2) // Assuming speedUpRate is initialized elsewhere
+		for _, k := range gameKeys {
+			if g.win.JustPressed(k.key) {
+				// Time spent saving a capture is not time played
+				start := time.Now()
+				k.do(g)
+				last = last.Add(time.Since(start))
 			}
-			g.levelUpTimer = levelLength
-			g.gravitySpeed = g.baseSpeed
 		}
 
-		g.processKeypresses()
+		dt := time.Since(last).Seconds()
+		last = time.Now()
+		if g.replay != nil {
+			g.playReplay(dt)
+		} else {
+			g.player.update(padInput{win: g.win, keys: g.player.keys, pad: defaultPad}, dt)
+		}
+		if g.player.finesse.takeFault() && g.settings.FinesseTraining == FinesseRestart && g.replay == nil {
+			g.stopRecording("the game restarted")
+			g.board.reset()
+			g.player.stats = newStats()
+			// The new game counts its pieces from the start again
+			g.hint = hint{}
+		}
+		if g.stream != nil {
+			g.stream.Publish(g.board.spectatorState())
+		}
 
 		g.win.Clear(colornames.Black)
 		g.board.displayBG(g.win)
-		g.board.displayText(g.win)
+		if g.settings.ShowStats {
+			g.board.displayText(g.win, g.player.stats.summary(g.settings.Language))
+		} else {
+			g.board.displayText(g.win, g.help())
+		}
 		g.board.displayBoard(g.win)
+		if g.settings.ShowHint && !g.config.Ranked {
+			g.hint.update(g.board)
+			if g.hint.found {
+				g.board.displayHint(g.win, g.hint.shape)
+			}
+			g.assisted = true
+		}
+		g.board.displayFinesse(g.win, &g.player.finesse, g.settings.FinesseTraining != FinesseOff)
+		g.board.displayRunFlag(g.win, g.runFlag())
 		g.win.Update()
 	}
+	g.end()
 }
 
-// 为tetrisGame结构体添加一个新方法来处理暂停的逻辑
-func (g *tetrisGame) togglePause(last time.Time) time.Time {
-	g.isPaused = !g.isPaused
-	if !g.isPaused {
-		// 重置时间以防止dt累积
-		last = time.Now()
+// end keeps what is left of the game once Run stops: its recording, and
+// the game itself if the window was closed while it was still going or its
+// stats if it finished.
+func (g *tetrisGame) end() {
+	if g.replay != nil {
+		return
 	}
-	return last
+	g.saveRecording()
+	if g.saved != nil {
+		// Closed at the menu, so the save is kept for next time
+		return
+	}
+	if !g.finished() && g.bot == nil {
+		g.persist()
+		return
+	}
+	g.saveStats()
 }
 
-// 显示暂停消息的方法
-func (g *tetrisGame) displayPausedMessage() {
-	g.board.displayPaused(g.win)
+// chooseContinue shows the menu offering to continue the saved game and
+// starts whichever game the player picks. The save is removed either way so
+// it cannot be continued twice.
+func (g *tetrisGame) chooseContinue() {
+	g.win.Clear(colornames.Black)
+	g.board.displayBG(g.win)
+	g.board.displayMessage(g.win, "C - Continue\nN - New game")
+	g.win.Update()
+
+	switch {
+	case g.win.JustPressed(pixelgl.KeyC):
+		g.restore(*g.saved)
+		g.stopRecording("the game was continued from a save")
+	case g.win.JustPressed(pixelgl.KeyN):
+	default:
+		return
+	}
+	g.saved = nil
+	if err := removeSave(); err != nil {
+		log.Printf("removing saved game: %v", err)
+	}
 }
 
-// Separated keypress handling for clarity
-func (g *tetrisGame) processKeypresses() {
-	if g.win.Pressed(pixelgl.KeyRight) && g.leftRightDelay == 0.0 {
-		g.handleHorizontalMove(1)
+// persist saves the game in progress so it can be continued next time.
+func (g *tetrisGame) persist() {
+	path, err := savePath()
+	if err == nil {
+		err = writeSave(path, g.saveGame())
 	}
-	if g.win.Pressed(pixelgl.KeyLeft) && g.leftRightDelay == 0.0 {
-		g.handleHorizontalMove(-1)
+	if err != nil {
+		log.Printf("saving game: %v", err)
+		g.saveStats()
 	}
-	if g.win.JustPressed(pixelgl.KeyDown) {
-		g.gravitySpeed = 0.08
-		if g.gravityTimer > 0.08 {
-			g.gravityTimer = 0.08
-		}
+}
+
+// finished reports whether the game has ended, by topping out, finishing a
+// sprint or reaching the end of the replay.
+func (g *tetrisGame) finished() bool {
+	switch {
+	case g.board.GameOver():
+		return true
+	case g.config.Mode == Sprint && g.board.lines >= sprintLines:
+		return true
+	case g.replay != nil && g.replayNext >= len(g.replay.Actions):
+		return true
 	}
-	if g.win.JustReleased(pixelgl.KeyDown) {
-		g.gravitySpeed = g.baseSpeed
+	return false
+}
+
+// runFlag describes the kind of run being played, for the side panel.
+func (g *tetrisGame) runFlag() string {
+	var flags []string
+	lang := g.settings.Language
+	if g.replay != nil {
+		flags = append(flags, lang.tr("Replay"))
+	}
+	if g.config.Ranked {
+		flags = append(flags, lang.tr("Ranked"))
+	} else if g.assisted {
+		flags = append(flags, lang.tr("Hints used"))
+	}
+	if g.config.Mode == Sprint {
+		flags = append(flags, fmt.Sprintf(lang.tr("Sprint %d/%d"), g.board.lines, sprintLines))
+	}
+	return strings.Join(flags, "  ")
+}
+
+// playReplay moves the replay on by dt seconds, making the actions that were
+// recorded in that time.
+func (g *tetrisGame) playReplay(dt float64) {
+	p := g.player
+	p.stats.tick(dt)
+	p.finesse.tick(dt)
+	actions := g.replay.Actions
+	for g.replayNext < len(actions) && actions[g.replayNext].Time <= p.stats.Seconds && !g.board.GameOver() {
+		p.apply(actions[g.replayNext].Action)
+		g.replayNext++
 	}
-	if g.win.JustPressed(pixelgl.KeyUp) {
-		g.board.rotatePiece()
-		if g.board.isTouchingFloor() {
-			g.gravityTimer = 0
+}
+
+// record adds an action made by the player to the replay.
+func (g *tetrisGame) record(a Action) {
+	if g.recording != nil {
+		g.recording.Actions = append(g.recording.Actions, TimedAction{Time: g.player.stats.Seconds, Action: a})
+	}
+}
+
+// stopRecording gives up on the replay, which can no longer reproduce the
+// game for the given reason.
+func (g *tetrisGame) stopRecording(reason string) {
+	if g.recording != nil && g.recordPath != "" {
+		log.Printf("not recording a replay: %s", reason)
+	}
+	g.recording = nil
+}
+
+// saveRecording writes the replay of the game, if one was recorded.
+func (g *tetrisGame) saveRecording() {
+	if g.recording == nil || g.recordPath == "" {
+		return
+	}
+	if err := g.recording.Save(g.recordPath); err != nil {
+		log.Printf("saving replay: %v", err)
+	}
+}
+
+// saveStats writes the stats of the game that just ended.
+func (g *tetrisGame) saveStats() {
+	path := g.config.StatsPath
+	if path == "" {
+		var err error
+		if path, err = defaultStatsPath(time.Now()); err != nil {
+			log.Printf("saving stats: %v", err)
+			return
 		}
 	}
-	if g.win.JustPressed(pixelgl.KeySpace) {
-		g.board.instafall()
-		g.board.AddScore(12)
+	if err := g.player.stats.Save(path); err != nil {
+		log.Printf("saving stats: %v", err)
+	}
+}
+
+// help is the controls help of the side panel, listing the buttons of the
+// gamepad too while one is connected.
+func (g *tetrisGame) help() string {
+	bs := singleBindings()
+	if _, ok := connectedPad(g.win); ok {
+		bs = append(bs, defaultPad.bindings()...)
+	}
+	return helpText(bs, g.settings.Language)
+}
+
+// toggleHint turns the placement hint on or off and saves the choice. Hints
+// cannot be turned on in a ranked run.
+func (g *tetrisGame) toggleHint() {
+	if g.config.Ranked {
+		return
 	}
-	if !g.win.Pressed(pixelgl.KeyRight) && !g.win.Pressed(pixelgl.KeyLeft) {
-		g.moveCounter = 0
-		g.leftRightDelay = 0
+	g.settings.ShowHint = !g.settings.ShowHint
+	g.saveSettings()
+}
+
+func (g *tetrisGame) toggleStats() {
+	g.settings.ShowStats = !g.settings.ShowStats
+	g.saveSettings()
+}
+
+func (g *tetrisGame) cycleFinesse() {
+	g.settings.FinesseTraining = g.settings.FinesseTraining.next()
+	g.saveSettings()
+}
+
+func (g *tetrisGame) cyclePalette() {
+	g.settings.Palette = g.settings.Palette.next()
+	g.restyle()
+}
+
+func (g *tetrisGame) toggleGlyphs() {
+	g.settings.Glyphs = !g.settings.Glyphs
+	g.restyle()
+}
+
+func (g *tetrisGame) cycleGhost() {
+	g.settings.Ghost = g.settings.Ghost.next()
+	g.restyle()
+}
+
+func (g *tetrisGame) cycleLanguage() {
+	g.settings.Language = g.settings.Language.next()
+	g.win.SetTitle(g.settings.Language.tr("Tetris"))
+	g.restyle()
+}
+
+// restyle redraws the board in the palette, glyphs, ghost and language of
+// the settings and saves the choice.
+func (g *tetrisGame) restyle() {
+	g.board.useSettings(g.settings)
+	g.saveSettings()
+}
+
+func (g *tetrisGame) saveSettings() {
+	if err := g.settings.Save(); err != nil {
+		log.Printf("saving settings: %v", err)
 	}
 }
 
-// Handle left/right movement
-func (g *tetrisGame) handleHorizontalMove(direction int) {
-	g.board.movePiece(direction)
-	if g.moveCounter > 0 {
-		g.leftRightDelay = 0.1
-	} else {
-		g.leftRightDelay = 0.5
+// togglePause pauses or resumes the game, returning the time the next frame
+// should measure from.
+func (g *tetrisGame) togglePause(last time.Time) time.Time {
+	g.isPaused = !g.isPaused
+	if !g.isPaused {
+		// Reset the time so dt does not build up while paused
+		last = time.Now()
 	}
-	g.moveCounter++
+	return last
+}
+
+// displayPausedMessage shows that the game is paused.
+func (g *tetrisGame) displayPausedMessage() {
+	g.board.displayPaused(g.win)
 }
@@ -0,0 +1,31 @@
+package tetris
+
+import "github.com/yankooo/tetris-go/tetris/spritesheet"
+
+// theme is the art boards are drawn with, the built in theme until UseTheme
+// is called.
+var theme *spritesheet.Theme
+
+// UseTheme makes boards be drawn with the theme in the directory or zip file
+// at path from now on. Returns an error, leaving the theme as it was, if the
+// theme cannot be loaded.
+func UseTheme(path string) error {
+	t, err := spritesheet.LoadTheme(path)
+	if err != nil {
+		return err
+	}
+	theme = t
+	return nil
+}
+
+// currentTheme returns the theme boards are drawn with.
+func currentTheme() *spritesheet.Theme {
+	if theme == nil {
+		t, err := spritesheet.DefaultTheme()
+		if err != nil {
+			panic(any(err))
+		}
+		theme = t
+	}
+	return theme
+}
@@ -0,0 +1,170 @@
+//go:build !js
+
+package tetris
+
+import (
+	"time"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+	"golang.org/x/image/colornames"
+)
+
+// versusKeys are the layouts of the two players sharing the keyboard in a
+// versus match. The first player sits on the left.
+var versusKeys = [2]keyMap{
+	{
+		left:      pixelgl.KeyA,
+		right:     pixelgl.KeyD,
+		softDrop:  pixelgl.KeyS,
+		rotate:    pixelgl.KeyW,
+		rotateCCW: []button{pixelgl.KeyQ},
+		rotate180: pixelgl.KeyE,
+		hardDrop:  pixelgl.KeySpace,
+	},
+	{
+		left:      pixelgl.KeyLeft,
+		right:     pixelgl.KeyRight,
+		softDrop:  pixelgl.KeyDown,
+		rotate:    pixelgl.KeyUp,
+		rotateCCW: []button{pixelgl.KeyRightControl},
+		rotate180: pixelgl.KeyRightShift,
+		hardDrop:  pixelgl.KeyEnter,
+	},
+}
+
+// versusGame is a local two player match. Both boards are drawn side by side
+// in one window and receive the same sequence of pieces. Rows cleared by one
+// player are sent to the other as garbage.
+type versusGame struct {
+	config  Config
+	win     *pixelgl.Window
+	players [2]*player
+
+	isOver   bool
+	winner   int // Index of the winning player, -1 for a draw
+	isPaused bool
+	screen   pixel.Matrix // Fits both halves to the window, updated every frame
+}
+
+// NewVersusGame creates a local match whose boards are set up by cfg. The
+// match lasts until a player tops out, whatever the mode of cfg. Returns an
+// error if cfg is not valid.
+func NewVersusGame(cfg Config) (*versusGame, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+	g := &versusGame{config: cfg}
+	return g, nil
+}
+
+func (g *versusGame) Initialize() {
+	settings := savedSettings()
+	g.win = newWindow(2*windowWidth, settings.Language)
+
+	for i := range g.players {
+		b := newRuledBoard(g.config.Seed, g.config.Ruleset, g.config.Preview, g.config.pieceSet())
+		b.useSettings(settings)
+		b.AddPiece()
+		g.players[i] = newPlayer(b, versusKeys[i])
+		g.players[i].baseSpeed = levelSpeed(g.config.Level)
+		g.players[i].gravitySpeed = g.players[i].baseSpeed
+	}
+}
+
+func (g *versusGame) Run() {
+	last := time.Now()
+	for !g.win.Closed() {
+		g.screen = fitWindow(g.win, 2*windowWidth)
+		if !g.isOver && g.win.JustPressed(pixelgl.MouseButtonLeft) {
+			g.isPaused = !g.isPaused
+			last = time.Now()
+		}
+
+		if g.isPaused {
+			for i, p := range g.players {
+				g.win.SetMatrix(playerMatrix(i).Chained(g.screen))
+				p.board.displayPaused(g.win)
+			}
+			g.win.SetMatrix(pixel.IM)
+			g.win.Update()
+			continue
+		}
+
+		dt := time.Since(last).Seconds()
+		last = time.Now()
+		if !g.isOver {
+			for _, p := range g.players {
+				p.update(g.win, dt)
+			}
+			g.exchangeGarbage()
+			g.checkWinner()
+		}
+
+		g.draw()
+		g.win.Update()
+	}
+}
+
+// exchangeGarbage passes the rows each board has sent on to its opponent.
+func (g *versusGame) exchangeGarbage() {
+	for i, p := range g.players {
+		g.players[1-i].board.QueueGarbage(p.board.TakeAttack())
+	}
+}
+
+// checkWinner ends the match once a board tops out, awarding it to the other
+// player. If both top out on the same frame the higher score wins.
+func (g *versusGame) checkWinner() {
+	lost := [2]bool{g.players[0].board.GameOver(), g.players[1].board.GameOver()}
+	if !lost[0] && !lost[1] {
+		return
+	}
+	g.isOver = true
+	switch {
+	case !lost[0]:
+		g.winner = 0
+	case !lost[1]:
+		g.winner = 1
+	case g.players[0].board.score > g.players[1].board.score:
+		g.winner = 0
+	case g.players[1].board.score > g.players[0].board.score:
+		g.winner = 1
+	default:
+		g.winner = -1
+	}
+}
+
+func (g *versusGame) draw() {
+	g.win.Clear(colornames.Black)
+	// Backgrounds overlap, so they all go down before anything on top of them
+	for i, p := range g.players {
+		g.win.SetMatrix(playerMatrix(i).Chained(g.screen))
+		p.board.displayBG(g.win)
+	}
+	for i, p := range g.players {
+		g.win.SetMatrix(playerMatrix(i).Chained(g.screen))
+		p.board.displayText(g.win, helpText(append(p.keys.bindings(), pauseBinding), p.board.lang))
+		p.board.displayBoard(g.win)
+		if g.isOver {
+			switch g.winner {
+			case -1:
+				p.board.displayMessage(g.win, "Draw")
+			case i:
+				p.board.displayMessage(g.win, "You Win")
+			default:
+				p.board.displayMessage(g.win, "You Lose")
+			}
+		}
+	}
+	g.win.SetMatrix(pixel.IM)
+}
+
+// playerMatrix moves drawing into the half of the window belonging to the
+// player at index i.
+func playerMatrix(i int) pixel.Matrix {
+	return pixel.IM.Moved(pixel.V(float64(i)*windowWidth, 0))
+}
@@ -0,0 +1,320 @@
+//go:build js && wasm
+
+package tetris
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"syscall/js"
+
+	"github.com/faiface/pixel"
+	"github.com/yankooo/tetris-go/tetris/spritesheet"
+)
+
+// webPauseKey pauses the game in the browser, as does clicking the canvas.
+const webPauseKey = "KeyP"
+
+// webPauseBinding lists the ways to pause in the controls help.
+var webPauseBinding = binding{[]string{buttonName(webPauseKey), "Click"}, "Pause"}
+
+// webInput is the state of the keyboard in a browser, kept up to date by
+// key event listeners. Listeners and frames both run on the browser's event
+// loop, so they never overlap.
+type webInput struct {
+	pressed      map[button]bool
+	justPressed  map[button]bool
+	justReleased map[button]bool
+}
+
+func newWebInput() *webInput {
+	in := &webInput{pressed: make(map[button]bool)}
+	in.endFrame()
+	return in
+}
+
+// keyDown records a key going down. Repeats sent while the key is held are
+// not counted as new presses.
+func (in *webInput) keyDown(code button) {
+	if !in.pressed[code] {
+		in.justPressed[code] = true
+	}
+	in.pressed[code] = true
+}
+
+// keyUp records a key being let go.
+func (in *webInput) keyUp(code button) {
+	delete(in.pressed, code)
+	in.justReleased[code] = true
+}
+
+func (in *webInput) Pressed(b button) bool {
+	return in.pressed[b]
+}
+
+func (in *webInput) JustPressed(b button) bool {
+	return in.justPressed[b]
+}
+
+func (in *webInput) JustReleased(b button) bool {
+	return in.justReleased[b]
+}
+
+// endFrame forgets the presses and releases made before the frame that was
+// just played.
+func (in *webInput) endFrame() {
+	in.justPressed = make(map[button]bool)
+	in.justReleased = make(map[button]bool)
+}
+
+// webGame plays a single player game in a browser, drawing on a canvas with
+// the same block sprites as the window.
+type webGame struct {
+	canvasID string
+	ctx      js.Value
+	board    *Board
+	player   *player
+	input    *webInput
+
+	theme      *spritesheet.Theme
+	sprites    []js.Value // Canvases holding the block sprites, by sprite index
+	background js.Value
+
+	isPaused bool
+	last     float64 // Time of the last frame, in milliseconds
+	done     chan struct{}
+	funcs    []js.Func // Callbacks to release when the game ends
+}
+
+// NewWebGame creates a game that is drawn on the canvas element with the
+// given id.
+func NewWebGame(canvasID string) *webGame {
+	return &webGame{canvasID: canvasID}
+}
+
+func (g *webGame) Initialize() {
+	doc := js.Global().Get("document")
+	canvas := doc.Call("getElementById", g.canvasID)
+	if canvas.IsNull() {
+		panic(fmt.Sprintf("tetris: no canvas with id %q", g.canvasID))
+	}
+	canvas.Set("width", screenLayout.width)
+	canvas.Set("height", screenLayout.height)
+	g.ctx = canvas.Call("getContext", "2d")
+
+	g.theme = currentTheme()
+	for i := 0; i < spritesheet.BlockSprites; i++ {
+		g.sprites = append(g.sprites, pictureCanvas(doc, g.theme.Blocks(i)))
+	}
+	g.background = pictureCanvas(doc, g.theme.Background)
+
+	g.board = NewBoard()
+	g.board.AddPiece()
+	g.player = newPlayer(g.board, defaultKeys)
+	g.input = newWebInput()
+
+	keys := append([]button{defaultKeys.left, defaultKeys.right, defaultKeys.softDrop, defaultKeys.rotate, defaultKeys.rotate180, defaultKeys.hardDrop}, defaultKeys.rotateCCW...)
+	g.listen(js.Global(), "keydown", func(ev js.Value) {
+		code := ev.Get("code").String()
+		for _, k := range keys {
+			// Stop the arrows and space from scrolling the page
+			if code == k {
+				ev.Call("preventDefault")
+			}
+		}
+		g.input.keyDown(code)
+	})
+	g.listen(js.Global(), "keyup", func(ev js.Value) {
+		g.input.keyUp(ev.Get("code").String())
+	})
+	g.listen(canvas, "click", func(js.Value) {
+		g.togglePause()
+	})
+}
+
+// listen calls fn with the events of type name sent to target until the
+// game ends.
+func (g *webGame) listen(target js.Value, name string, fn func(js.Value)) {
+	f := js.FuncOf(func(this js.Value, args []js.Value) any {
+		fn(args[0])
+		return nil
+	})
+	g.funcs = append(g.funcs, f)
+	target.Call("addEventListener", name, f)
+}
+
+// Run plays the game, one frame per animation frame of the browser, and
+// returns once it is over.
+func (g *webGame) Run() {
+	g.done = make(chan struct{})
+	var frame js.Func
+	frame = js.FuncOf(func(this js.Value, args []js.Value) any {
+		now := args[0].Float()
+		if g.last == 0 {
+			g.last = now
+		}
+		dt := (now - g.last) / 1000
+		g.last = now
+
+		if g.input.JustPressed(webPauseKey) {
+			g.togglePause()
+		}
+		if !g.isPaused {
+			g.player.update(g.input, dt)
+		}
+		g.input.endFrame()
+		g.draw()
+
+		if g.board.GameOver() {
+			close(g.done)
+			return nil
+		}
+		js.Global().Call("requestAnimationFrame", frame)
+		return nil
+	})
+	g.funcs = append(g.funcs, frame)
+	js.Global().Call("requestAnimationFrame", frame)
+	<-g.done
+
+	for _, f := range g.funcs {
+		f.Release()
+	}
+}
+
+func (g *webGame) togglePause() {
+	g.isPaused = !g.isPaused
+}
+
+// draw paints the board and side panel on the canvas, laid out as in the
+// window.
+func (g *webGame) draw() {
+	b := g.board
+	ctx := g.ctx
+	l := screenLayout
+	ctx.Call("clearRect", 0, 0, l.width, l.height)
+	bgWidth := g.background.Get("width").Float()
+	bgHeight := g.background.Get("height").Float()
+	ctx.Call("drawImage", g.background, (l.width-bgWidth)/2, (l.height-bgHeight)/2)
+
+	// Playing field, the score and the next piece
+	ctx.Set("fillStyle", cssColor(g.theme.Panel))
+	g.fillRect(l.fieldRect())
+	g.fillRect(pixel.R(-100, -15, 100, 15).Moved(l.scoreBox))
+	g.fillRect(pixel.R(-50, -50, 50, 50).Moved(l.nextBox))
+
+	g.drawGhost()
+	for row := 0; row < BoardRows-2; row++ {
+		for col := 0; col < BoardCols; col++ {
+			if val := b.board[row][col]; val != Empty {
+				g.drawBlock(val, l.cell(row, col))
+			}
+		}
+	}
+	for _, p := range b.activeShape {
+		if p.row < BoardRows-2 {
+			g.drawBlock(b.pieceBlock(b.currentPiece), l.cell(p.row, p.col))
+		}
+	}
+
+	next := b.set.shape(b.nextPiece)
+	minRow, maxRow, minCol, maxCol := shapeBounds(next)
+	width, height := float64(maxCol-minCol+1), float64(maxRow-minRow+1)
+	for _, p := range next {
+		x := l.nextBox.X + (float64(p.col-minCol)+0.5-width/2)*l.block
+		y := l.nextBox.Y + (float64(p.row-minRow)+0.5-height/2)*l.block
+		g.drawBlock(b.pieceBlock(b.nextPiece), pixel.V(x, y))
+	}
+
+	ctx.Set("fillStyle", cssColor(g.theme.Text))
+	ctx.Set("font", "26px monospace")
+	g.fillText("Score", l.score)
+	g.fillText("Next Piece", l.nextLabel)
+	ctx.Set("font", "20px monospace")
+	g.fillText(fmt.Sprint(b.score), l.score.Sub(pixel.V(0, 30)))
+	ctx.Set("font", "14px monospace")
+	for i, line := range strings.Split(strings.TrimSpace(helpText(append(g.player.keys.bindings(), webPauseBinding), English)), "\n") {
+		g.fillText(strings.TrimSpace(line), l.help.Sub(pixel.V(0, float64(i)*13)))
+	}
+
+	ctx.Set("font", "26px monospace")
+	switch {
+	case b.GameOver():
+		g.fillText("Game Over", l.message)
+	case g.isPaused:
+		g.fillText("Game Pause", l.message)
+	}
+}
+
+// drawGhost outlines where the active piece would land in the piece's
+// colour, like the window does by default. The blocks are drawn after it and
+// cover it where the piece overlaps its ghost.
+func (g *webGame) drawGhost() {
+	b := g.board
+	c := g.theme.BlockColor(block2spriteIdx(b.pieceBlock(b.currentPiece)))
+	ctx := g.ctx
+	ctx.Set("strokeStyle", cssColor(c))
+	ctx.Set("globalAlpha", ghostOutlineAlpha)
+	ctx.Set("lineWidth", 2)
+	ctx.Call("beginPath")
+	for _, e := range outlineEdges(b.ghostShape()) {
+		ctx.Call("moveTo", e[0].X, screenLayout.height-e[0].Y)
+		ctx.Call("lineTo", e[1].X, screenLayout.height-e[1].Y)
+	}
+	ctx.Call("stroke")
+	ctx.Set("globalAlpha", 1)
+}
+
+// The canvas has its origin at the top left, while the layout has it at the
+// bottom left like the window, so y is flipped on the way.
+
+// fillRect fills r, given in layout units, with the current fill style.
+func (g *webGame) fillRect(r pixel.Rect) {
+	g.ctx.Call("fillRect", r.Min.X, screenLayout.height-r.Max.Y, r.W(), r.H())
+}
+
+// fillText writes s with its baseline starting at v.
+func (g *webGame) fillText(s string, v pixel.Vec) {
+	g.ctx.Call("fillText", s, v.X, screenLayout.height-v.Y)
+}
+
+// drawBlock draws the sprite of block t centred on v.
+func (g *webGame) drawBlock(t Block, v pixel.Vec) {
+	size := screenLayout.block
+	g.ctx.Call("drawImage", g.sprites[block2spriteIdx(t)], v.X-size/2, screenLayout.height-v.Y-size/2, size, size)
+}
+
+// cssColor writes c, which has premultiplied alpha, as a CSS colour.
+func cssColor(c color.RGBA) string {
+	if c.A == 0 {
+		return "transparent"
+	}
+	a := float64(c.A) / 0xff
+	return fmt.Sprintf("rgba(%.0f, %.0f, %.0f, %.2f)", float64(c.R)/a, float64(c.G)/a, float64(c.B)/a, a)
+}
+
+// pictureCanvas copies pic onto a new canvas element so it can be drawn with
+// drawImage.
+func pictureCanvas(doc js.Value, pic pixel.Picture) js.Value {
+	img := pic.(*pixel.PictureData).Image()
+	bounds := img.Bounds()
+	canvas := doc.Call("createElement", "canvas")
+	canvas.Set("width", bounds.Dx())
+	canvas.Set("height", bounds.Dy())
+	pix := straightAlpha(img)
+	data := js.Global().Get("Uint8ClampedArray").New(len(pix))
+	js.CopyBytesToJS(data, pix)
+	imageData := js.Global().Get("ImageData").New(data, bounds.Dx(), bounds.Dy())
+	canvas.Call("getContext", "2d").Call("putImageData", imageData, 0, 0)
+	return canvas
+}
+
+// straightAlpha returns the pixels of img, whose colours are premultiplied
+// by their alpha as pixel keeps them, with straight alpha as ImageData takes
+// them.
+func straightAlpha(img *image.RGBA) []byte {
+	out := image.NewNRGBA(img.Bounds())
+	draw.Draw(out, out.Bounds(), img, img.Bounds().Min, draw.Src)
+	return out.Pix
+}
@@ -0,0 +1,64 @@
+//go:build js && wasm
+
+package tetris
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestWebInputPresses(t *testing.T) {
+	in := newWebInput()
+	in.keyDown("ArrowLeft")
+	in.keyDown("ArrowLeft") // A repeat while held
+	if !in.Pressed("ArrowLeft") || !in.JustPressed("ArrowLeft") {
+		t.Fatal("ArrowLeft should be pressed and just pressed")
+	}
+	in.endFrame()
+	in.keyDown("ArrowLeft")
+	if in.JustPressed("ArrowLeft") {
+		t.Error("a repeat while held counted as a new press")
+	}
+	in.keyUp("ArrowLeft")
+	if in.Pressed("ArrowLeft") || !in.JustReleased("ArrowLeft") {
+		t.Error("ArrowLeft should be just released")
+	}
+}
+
+func TestWebInputDrivesPlayer(t *testing.T) {
+	b := NewSeededBoard(1)
+	b.AddPiece()
+	p := newPlayer(b, defaultKeys)
+	in := newWebInput()
+
+	in.keyDown(defaultKeys.hardDrop)
+	p.update(in, 0)
+	in.endFrame()
+	if b.pieces != 2 {
+		t.Fatalf("pieces = %d after a hard drop, want 2", b.pieces)
+	}
+
+	start := b.activePose
+	in.keyDown(defaultKeys.right)
+	p.update(in, 0)
+	if b.activePose == start {
+		t.Error("holding right did not move the piece")
+	}
+}
+
+func TestStraightAlpha(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 1))
+	img.SetRGBA(0, 0, color.RGBA{R: 0x80, A: 0x80}) // Half opaque red
+	img.SetRGBA(1, 0, color.RGBA{R: 0x10, G: 0x20, B: 0x30, A: 0xff})
+	got := straightAlpha(img)
+	want := []byte{
+		0xff, 0, 0, 0x80,
+		0x10, 0x20, 0x30, 0xff,
+		0, 0, 0, 0,
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,41 @@
+//go:build !js
+
+package tetris
+
+import (
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+)
+
+// fullscreenKey switches the window between fullscreen and windowed.
+const fullscreenKey = pixelgl.KeyF11
+
+// newWindow opens a resizable game window fitting a screen of the given
+// width, in layout units, titled in language lang.
+func newWindow(width float64, lang Language) *pixelgl.Window {
+	cfg := pixelgl.WindowConfig{
+		Title:     lang.tr("Tetris"),
+		Bounds:    pixel.R(0, 0, width, windowHeight),
+		VSync:     true,
+		Resizable: true,
+	}
+	win, err := pixelgl.NewWindow(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return win
+}
+
+// fitWindow toggles fullscreen when fullscreenKey is pressed and returns the
+// matrix that fits a screen width layout units wide to the window as it is
+// now. It is called once a frame, before anything is drawn.
+func fitWindow(win *pixelgl.Window, width float64) pixel.Matrix {
+	if win.JustPressed(fullscreenKey) {
+		if win.Monitor() == nil {
+			win.SetMonitor(pixelgl.PrimaryMonitor())
+		} else {
+			win.SetMonitor(nil)
+		}
+	}
+	return screenLayout.fit(win.Bounds(), width)
+}
@@ -0,0 +1,16 @@
+//go:build js && wasm
+
+// Command web plays the game in a browser. Build it to WebAssembly and serve
+// it together with index.html and the wasm_exec.js that ships with Go:
+//
+//	GOOS=js GOARCH=wasm go build -o web/tetris.wasm ./web
+//	cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" web/
+package main
+
+import "github.com/yankooo/tetris-go/tetris"
+
+func main() {
+	g := tetris.NewWebGame("tetris")
+	g.Initialize()
+	g.Run()
+}